@@ -0,0 +1,151 @@
+package qjson
+
+import (
+    "reflect"
+)
+
+type appendKey int
+
+// Special key for U() which refers to position right past the end of array.
+// Updating value at this position appends new element to array. Missing
+// array is created.
+const Append appendKey = 0
+
+func (appendKey) String() string {
+    return "-"
+}
+
+// Replaces Append keys in U() arguments with concrete array indexes.
+func resolveAppend(V interface{}, keys []interface{}) []interface{} {
+    var res []interface{}
+    cur := V
+    for i := 0; i < len(keys)-1; i++ {
+        switch k := keys[i].(type) {
+        case appendKey:
+            if res == nil {
+                res = make([]interface{}, len(keys))
+                copy(res, keys)
+            }
+            res[i] = 0
+            if v := indirect(reflect.ValueOf(cur)); v.Kind() == reflect.Slice {
+                res[i] = v.Len()
+            }
+            cur = nil
+        default:
+            next, err := q(cur, k)
+            if err != nil {
+                next = nil
+            }
+            cur = next
+        }
+    }
+    if res == nil {
+        return keys
+    }
+    return res
+}
+
+func arrayOp(V *interface{}, keys []interface{}, fn func([]interface{}) ([]interface{}, error)) error {
+    if V == nil {
+        return newArgError("nil pointer dereference")
+    }
+    err := apply(V, keys, func(C interface{}) (interface{}, error) {
+        a, ok := C.([]interface{})
+        if !ok {
+            return nil, newTypeError("Bad container type: not an array")
+        }
+        return fn(a)
+    })
+    return withPath(err, keys)
+}
+
+// Insert value into array, shifting element at this position and all
+// subsequent elements.
+// Invocation: Insert(object *interface{}, path... interface{}, index int, newvalue interface{}).
+// Index equal to array length appends value to the end of array.
+func Insert(V *interface{}, keys ...interface{}) error {
+    l := len(keys)
+    if l < 2 {
+        return newArgError("Incorrect arg length")
+    }
+    idx, ok := keys[l-2].(int)
+    if !ok {
+        return newTypeError("Index must be int")
+    }
+    value := keys[l-1]
+    path := keys[:l-2]
+    return arrayOp(V, path, func(a []interface{}) ([]interface{}, error) {
+        if idx < 0 || idx > len(a) {
+            // Index is part of location, like in errors of Q()
+            return nil, locate(newIndexError(idx), append(append([]interface{}{}, path...), idx), len(path), a)
+        }
+        a = append(a, nil)
+        copy(a[idx+1:], a[idx:])
+        a[idx] = value
+        return a, nil
+    })
+}
+
+// Insert value at the beginning of array.
+// Invocation: Prepend(object *interface{}, path... interface{}, newvalue interface{}).
+func Prepend(V *interface{}, keys ...interface{}) error {
+    l := len(keys)
+    if l < 1 {
+        return newArgError("Incorrect arg length")
+    }
+    args := make([]interface{}, 0, l+1)
+    args = append(args, keys[:l-1]...)
+    args = append(args, 0, keys[l-1])
+    return Insert(V, args...)
+}
+
+// Remove last element of array.
+// Invocation: Pop(object *interface{}, path... interface{}).
+// Returns removed element and error. ArgError is returned for empty array.
+func Pop(V *interface{}, keys ...interface{}) (interface{}, error) {
+    var removed interface{}
+    err := arrayOp(V, keys, func(a []interface{}) ([]interface{}, error) {
+        if len(a) == 0 {
+            return nil, newArgError("Can't pop from empty array")
+        }
+        removed = a[len(a)-1]
+        a[len(a)-1] = nil
+        return a[:len(a)-1], nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return removed, nil
+}
+
+// Remove deleteCount elements of array starting at index start and insert
+// items in their place. Like in JavaScript, deleteCount running past the end
+// of array removes all elements starting at start.
+// Invocation: Splice(object *interface{}, start int, deleteCount int, items []interface{}, path... interface{}).
+// Returns removed elements and error.
+func Splice(V *interface{}, start, deleteCount int, items []interface{}, keys ...interface{}) ([]interface{}, error) {
+    if deleteCount < 0 {
+        return nil, newArgError("Negative delete count is not allowed")
+    }
+    var removed []interface{}
+    err := arrayOp(V, keys, func(a []interface{}) ([]interface{}, error) {
+        if start < 0 || start > len(a) {
+            return nil, newIndexError(start)
+        }
+        count := deleteCount
+        if count > len(a)-start {
+            count = len(a) - start
+        }
+        removed = make([]interface{}, count)
+        copy(removed, a[start:start+count])
+        res := make([]interface{}, 0, len(a)-count+len(items))
+        res = append(res, a[:start]...)
+        res = append(res, items...)
+        res = append(res, a[start+count:]...)
+        return res, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return removed, nil
+}
@@ -0,0 +1,154 @@
+package qjson
+
+import (
+    "math"
+    "testing"
+)
+
+func TestUpdateAppend(t *testing.T) {
+    var k interface{}
+    U(&k, "menu", "popup", "menuitem", Append, "value", "New")
+    U(&k, "menu", "popup", "menuitem", 0, "onclick", "CreateNewDoc()")
+    U(&k, "menu", "popup", "menuitem", Append, "value", "Open")
+    U(&k, "menu", "popup", "menuitem", 1, "onclick", "OpenDoc()")
+    U(&k, "menu", "popup", "menuitem", Append, map[string]interface{}{
+        "value": "Close",
+        "onclick": "CloseDoc()",
+    })
+    U(&k, "menu", "id", "file")
+    U(&k, "menu", "value", "File")
+    if dumpJSON(k, t) != dumpJSON(loadJSON(EXAMPLE2, t), t) {
+        t.Fail()
+    }
+
+    var j interface{}
+    U(&j, Append, "a")
+    U(&j, Append, "b")
+    U(&j, Append, Append, "c")
+    if dumpJSON(j, t) != `["a","b",["c"]]` {
+        t.Fail()
+    }
+
+    j = loadJSON(`{"a": {}}`, t)
+    _, err := U(&j, "a", Append, 1)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+}
+
+func TestInsert(t *testing.T) {
+    j := loadJSON(`{"a": [1, 2, 3]}`, t)
+    if err := Insert(&j, "a", 1, "x"); err != nil {
+        t.Fail()
+    }
+    if err := Insert(&j, "a", 4, "y"); err != nil {
+        t.Fail()
+    }
+    if err := Prepend(&j, "a", "z"); err != nil {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":["z",1,"x",2,3,"y"]}` {
+        t.Fail()
+    }
+    err := Insert(&j, "a", 7, "y")
+    if e, ok := err.(IndexError) ; !ok || e.Index() != 7 || FormatPath(e.Path()...) != "a[7]" || e.Depth() != 1 {
+        t.Fail()
+    }
+    err = Insert(&j, "a", "b", "y")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    err = Insert(&j, 0, "y")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    err = Insert(&j, "b", 0, "y")
+    if _, ok := err.(KeyError) ; !ok {
+        t.Fail()
+    }
+    err = Insert(&j, 0)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+    err = Prepend(&j)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+    err = Insert(nil, 0, 0)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+
+    j = loadJSON(`[]`, t)
+    Insert(&j, 0, "a")
+    Prepend(&j, "b")
+    if dumpJSON(j, t) != `["b","a"]` {
+        t.Fail()
+    }
+}
+
+func TestPop(t *testing.T) {
+    j := loadJSON(`[[1, 2]]`, t)
+    v, err := Pop(&j, 0)
+    if err != nil || v != 2. {
+        t.Fail()
+    }
+    v, err = Pop(&j, 0)
+    if err != nil || v != 1. {
+        t.Fail()
+    }
+    _, err = Pop(&j, 0)
+    if e, ok := err.(ArgError) ; !ok || FormatPath(e.Path()...) != "[0]" {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `[[]]` {
+        t.Fail()
+    }
+    _, err = Pop(&j, 0, 0)
+    if _, ok := err.(IndexError) ; !ok {
+        t.Fail()
+    }
+}
+
+func TestSplice(t *testing.T) {
+    j := loadJSON(`{"a": [1, 2, 3, 4]}`, t)
+    removed, err := Splice(&j, 1, 2, []interface{}{"x", "y", "z"}, "a")
+    if err != nil || dumpJSON(removed, t) != `[2,3]` {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":[1,"x","y","z",4]}` {
+        t.Fail()
+    }
+    removed, err = Splice(&j, 5, 0, []interface{}{5}, "a")
+    if err != nil || len(removed) != 0 {
+        t.Fail()
+    }
+    removed, err = Splice(&j, 0, 4, nil, "a")
+    if err != nil || dumpJSON(removed, t) != `[1,"x","y","z"]` {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":[4,5]}` {
+        t.Fail()
+    }
+    _, err = Splice(&j, 3, 0, nil, "a")
+    if _, ok := err.(IndexError) ; !ok {
+        t.Fail()
+    }
+    // Delete count is clamped to the end of array
+    removed, err = Splice(&j, 1, 2, []interface{}{6, 7}, "a")
+    if err != nil || dumpJSON(removed, t) != `[5]` || dumpJSON(j, t) != `{"a":[4,6,7]}` {
+        t.Fail()
+    }
+    removed, err = Splice(&j, 1, math.MaxInt, nil, "a")
+    if err != nil || dumpJSON(removed, t) != `[6,7]` || dumpJSON(j, t) != `{"a":[4]}` {
+        t.Fail()
+    }
+    _, err = Splice(&j, 0, -1, nil, "a")
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+    _, err = Splice(&j, 0, 0, nil)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+}
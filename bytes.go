@@ -0,0 +1,510 @@
+package qjson
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strconv"
+)
+
+// Nesting depth limit of recursive scanners. It matches the limit of
+// encoding/json and protects them from exhausting the stack.
+const defaultMaxDepth = 10000
+
+// Byte-level JSON scanner. It validates syntax of scanned values and
+// tracks position in input without building any values.
+type scanner struct {
+    data  []byte
+    pos   int
+    path  []interface{}
+    depth int
+}
+
+func (s *scanner) fail(format string, args ...interface{}) error {
+    return ParseError{
+        offset: int64(s.pos),
+        path:   append([]interface{}{}, s.path...),
+        msg:    fmt.Sprintf(format, args...),
+        kind:   ErrSyntax,
+    }
+}
+
+// Enters nested container. Fails once nesting exceeds defaultMaxDepth.
+func (s *scanner) enter() error {
+    s.depth++
+    if s.depth > defaultMaxDepth {
+        return ParseError{
+            offset: int64(s.pos),
+            path:   append([]interface{}{}, s.path...),
+            msg:    fmt.Sprintf("Nesting depth exceeds %d", defaultMaxDepth),
+            kind:   ErrLimitExceeded,
+        }
+    }
+    return nil
+}
+
+func (s *scanner) ws() {
+    for s.pos < len(s.data) {
+        switch s.data[s.pos] {
+        case ' ', '\t', '\n', '\r':
+            s.pos++
+        default:
+            return
+        }
+    }
+}
+
+// Returns current byte or 0 at the end of input.
+func (s *scanner) peek() byte {
+    if s.pos < len(s.data) {
+        return s.data[s.pos]
+    }
+    return 0
+}
+
+func (s *scanner) expect(c byte) error {
+    s.ws()
+    if s.peek() != c {
+        return s.unexpected(fmt.Sprintf("%q", c))
+    }
+    s.pos++
+    return nil
+}
+
+func (s *scanner) unexpected(what string) error {
+    if s.pos >= len(s.data) {
+        return s.fail("Unexpected end of input, expecting %s", what)
+    }
+    return s.fail("Unexpected character %q, expecting %s", s.data[s.pos], what)
+}
+
+// Scans value starting at current position, possibly after whitespace.
+// Returns span of the value.
+func (s *scanner) value() (int, int, error) {
+    s.ws()
+    start := s.pos
+    var err error
+    switch c := s.peek(); {
+    case c == '{':
+        err = s.object()
+    case c == '[':
+        err = s.array()
+    case c == '"':
+        err = s.str()
+    case c == 't':
+        err = s.literal("true")
+    case c == 'f':
+        err = s.literal("false")
+    case c == 'n':
+        err = s.literal("null")
+    case c == '-' || c >= '0' && c <= '9':
+        err = s.number()
+    default:
+        err = s.unexpected("value")
+    }
+    return start, s.pos, err
+}
+
+func (s *scanner) literal(lit string) error {
+    if !bytes.HasPrefix(s.data[s.pos:], []byte(lit)) {
+        return s.fail("Invalid literal, expecting %s", lit)
+    }
+    s.pos += len(lit)
+    return nil
+}
+
+func (s *scanner) digits() int {
+    n := 0
+    for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+        s.pos++
+        n++
+    }
+    return n
+}
+
+func (s *scanner) number() error {
+    if s.peek() == '-' {
+        s.pos++
+    }
+    if s.peek() == '0' {
+        s.pos++
+    } else if s.digits() == 0 {
+        return s.unexpected("digit")
+    }
+    if s.peek() == '.' {
+        s.pos++
+        if s.digits() == 0 {
+            return s.unexpected("digit")
+        }
+    }
+    if c := s.peek(); c == 'e' || c == 'E' {
+        s.pos++
+        if c := s.peek(); c == '+' || c == '-' {
+            s.pos++
+        }
+        if s.digits() == 0 {
+            return s.unexpected("digit")
+        }
+    }
+    return nil
+}
+
+func (s *scanner) str() error {
+    s.pos++
+    for s.pos < len(s.data) {
+        c := s.data[s.pos]
+        switch {
+        case c == '"':
+            s.pos++
+            return nil
+        case c == '\\':
+            s.pos++
+            switch s.peek() {
+            case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+                s.pos++
+            case 'u':
+                s.pos++
+                for i := 0; i < 4; i++ {
+                    c := s.peek()
+                    if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+                        return s.unexpected("hex digit")
+                    }
+                    s.pos++
+                }
+            default:
+                return s.unexpected("escape sequence")
+            }
+        case c < 0x20:
+            return s.fail("Control character %q in string", c)
+        default:
+            s.pos++
+        }
+    }
+    return s.fail("Unterminated string")
+}
+
+func (s *scanner) object() error {
+    if err := s.enter(); err != nil {
+        return err
+    }
+    defer func() { s.depth-- }()
+    s.pos++
+    s.ws()
+    if s.peek() == '}' {
+        s.pos++
+        return nil
+    }
+    for {
+        if _, err := s.key(); err != nil {
+            return err
+        }
+        if _, _, err := s.value(); err != nil {
+            return err
+        }
+        s.ws()
+        switch s.peek() {
+        case ',':
+            s.pos++
+        case '}':
+            s.pos++
+            return nil
+        default:
+            return s.unexpected("',' or '}'")
+        }
+    }
+}
+
+func (s *scanner) array() error {
+    if err := s.enter(); err != nil {
+        return err
+    }
+    defer func() { s.depth-- }()
+    s.pos++
+    s.ws()
+    if s.peek() == ']' {
+        s.pos++
+        return nil
+    }
+    for {
+        if _, _, err := s.value(); err != nil {
+            return err
+        }
+        s.ws()
+        switch s.peek() {
+        case ',':
+            s.pos++
+        case ']':
+            s.pos++
+            return nil
+        default:
+            return s.unexpected("',' or ']'")
+        }
+    }
+}
+
+// Scans object member key and following colon. Returns raw key including
+// quotes.
+func (s *scanner) key() ([]byte, error) {
+    s.ws()
+    if s.peek() != '"' {
+        return nil, s.unexpected("string key")
+    }
+    start := s.pos
+    if err := s.str(); err != nil {
+        return nil, err
+    }
+    raw := s.data[start:s.pos]
+    return raw, s.expect(':')
+}
+
+// Reports if raw JSON string equals k.
+func keyEquals(raw []byte, k string) bool {
+    body := raw[1 : len(raw)-1]
+    if bytes.IndexByte(body, '\\') < 0 {
+        return string(body) == k
+    }
+    var decoded string
+    if err := json.Unmarshal(raw, &decoded); err != nil {
+        return false
+    }
+    return decoded == k
+}
+
+// Returns value of the same kind as raw JSON value starting with byte c.
+func byteKind(c byte) interface{} {
+    switch c {
+    case '{':
+        return map[string]interface{}(nil)
+    case '[':
+        return []interface{}(nil)
+    case '"':
+        return ""
+    case 't', 'f':
+        return false
+    case 'n':
+        return nil
+    default:
+        return 0.0
+    }
+}
+
+// Moves position from start of container to start of value addressed by
+// key. First occurrence of repeated key is used.
+func (s *scanner) follow(key interface{}) error {
+    switch k := key.(type) {
+    case string:
+        if s.peek() != '{' {
+            if _, _, err := s.value(); err != nil {
+                return err
+            }
+            return newTypeError("Bad container type: not a map")
+        }
+        s.pos++
+        s.ws()
+        if s.peek() == '}' {
+            return newKeyError(k)
+        }
+        for {
+            raw, err := s.key()
+            if err != nil {
+                return err
+            }
+            s.ws()
+            if keyEquals(raw, k) {
+                s.path = append(s.path, k)
+                return nil
+            }
+            if _, _, err := s.value(); err != nil {
+                return err
+            }
+            s.ws()
+            switch s.peek() {
+            case ',':
+                s.pos++
+            case '}':
+                return newKeyError(k)
+            default:
+                return s.unexpected("',' or '}'")
+            }
+        }
+    case int:
+        if s.peek() != '[' {
+            if _, _, err := s.value(); err != nil {
+                return err
+            }
+            return newTypeError("Bad container type: not an array")
+        }
+        if k < 0 {
+            return newIndexError(k)
+        }
+        s.pos++
+        s.ws()
+        if s.peek() == ']' {
+            return newIndexError(k)
+        }
+        for i := 0; ; i++ {
+            s.ws()
+            if i == k {
+                s.path = append(s.path, k)
+                return nil
+            }
+            if _, _, err := s.value(); err != nil {
+                return err
+            }
+            s.ws()
+            switch s.peek() {
+            case ',':
+                s.pos++
+            case ']':
+                return newIndexError(k)
+            default:
+                return s.unexpected("',' or ']'")
+            }
+        }
+    case wildcardKey:
+        return newArgError("Wildcard keys are supported only by QAll()")
+    default:
+        return newTypeError("Unknown key type")
+    }
+}
+
+// Follows path and scans value found there. Returns its span.
+func (s *scanner) lookup(keys []interface{}) (int, int, error) {
+    s.ws()
+    for i, key := range keys {
+        kind := byteKind(s.peek())
+        if err := s.follow(key); err != nil {
+            return 0, 0, locate(err, keys, i, kind)
+        }
+    }
+    start, end, err := s.value()
+    if err != nil {
+        return 0, 0, err
+    }
+    // Check that value is properly terminated
+    s.ws()
+    if len(keys) == 0 {
+        if s.pos < len(s.data) {
+            return 0, 0, s.unexpected("end of input")
+        }
+        return start, end, nil
+    }
+    switch c := s.peek(); {
+    case c == ',':
+    case c == '}' && isKey(keys[len(keys)-1]):
+    case c == ']' && !isKey(keys[len(keys)-1]):
+    default:
+        return 0, 0, s.unexpected("',' or closing bracket")
+    }
+    return start, end, nil
+}
+
+func isKey(key interface{}) bool {
+    _, ok := key.(string)
+    return ok
+}
+
+// Same as Q(), but works with raw JSON data without decoding it. Returns
+// span of data holding value found at path. Returned slice shares memory
+// with data. Unlike Q(), first occurrence of repeated key is used. Values
+// preceding the result are validated, but not decoded, and data following
+// it is not scanned at all. Malformed input is reported with ParseError,
+// values nested deeper than 10000 levels are rejected with ParseError
+// matching ErrLimitExceeded.
+func QBytes(data []byte, keys ...interface{}) ([]byte, error) {
+    s := scanner{data: data}
+    start, end, err := s.lookup(keys)
+    if err != nil {
+        return nil, err
+    }
+    return data[start:end:end], nil
+}
+
+// Same as QBytes(), but decodes retrieved string. If value is not a string
+// TypeError is returned.
+func QBytesString(data []byte, keys ...interface{}) (string, error) {
+    raw, err := QBytes(data, keys...)
+    if err != nil {
+        return "", err
+    }
+    if raw[0] != '"' {
+        return "", locate(newTypeError("Retrieved value is not a string"), keys, len(keys), byteKind(raw[0]))
+    }
+    body := raw[1 : len(raw)-1]
+    if bytes.IndexByte(body, '\\') < 0 {
+        return string(body), nil
+    }
+    var res string
+    if err := json.Unmarshal(raw, &res); err != nil {
+        return "", locate(newTypeError(err.Error()), keys, len(keys), res)
+    }
+    return res, nil
+}
+
+// Same as QBytes(), but parses retrieved number as float64. If value is not
+// a number TypeError is returned.
+func QBytesNumber(data []byte, keys ...interface{}) (float64, error) {
+    raw, err := QBytes(data, keys...)
+    if err != nil {
+        return 0, err
+    }
+    if _, ok := byteKind(raw[0]).(float64); !ok {
+        return 0, locate(newTypeError("Retrieved value is not a number"), keys, len(keys), byteKind(raw[0]))
+    }
+    res, err := toFloat64(json.Number(raw))
+    if err != nil {
+        return 0, locate(err, keys, len(keys), json.Number(raw))
+    }
+    return res, nil
+}
+
+// Same as QBytes(), but parses retrieved number as int64. If value is not
+// an integer TypeError is returned, RangeError is returned if it doesn't
+// fit into int64.
+func QBytesInt64(data []byte, keys ...interface{}) (int64, error) {
+    raw, err := QBytes(data, keys...)
+    if err != nil {
+        return 0, err
+    }
+    if _, ok := byteKind(raw[0]).(float64); !ok {
+        return 0, locate(newTypeError("Retrieved value is not a number"), keys, len(keys), byteKind(raw[0]))
+    }
+    if res, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+        return res, nil
+    }
+    res, err := toInt64(json.Number(raw))
+    if err != nil {
+        return 0, locate(err, keys, len(keys), json.Number(raw))
+    }
+    return res, nil
+}
+
+// Same as QBytes(), but parses retrieved boolean. If value is not a
+// boolean TypeError is returned.
+func QBytesBool(data []byte, keys ...interface{}) (bool, error) {
+    raw, err := QBytes(data, keys...)
+    if err != nil {
+        return false, err
+    }
+    switch raw[0] {
+    case 't':
+        return true, nil
+    case 'f':
+        return false, nil
+    default:
+        return false, locate(newTypeError("Retrieved value is not a boolean"), keys, len(keys), byteKind(raw[0]))
+    }
+}
+
+// Same as QBytes(), but checks if value is null. If not, TypeError is
+// returned.
+func QBytesNull(data []byte, keys ...interface{}) error {
+    raw, err := QBytes(data, keys...)
+    if err != nil {
+        return err
+    }
+    if raw[0] != 'n' {
+        return locate(newTypeError("Retrieved value is not null"), keys, len(keys), byteKind(raw[0]))
+    }
+    return nil
+}
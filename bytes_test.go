@@ -0,0 +1,144 @@
+package qjson
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestQBytes(t *testing.T) {
+    data := []byte(EXAMPLE)
+    j := loadJSON(EXAMPLE, t)
+    paths := [][]interface{}{
+        {},
+        {"glossary"},
+        {"glossary", "title"},
+        {"glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossDef"},
+        {"glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossDef", "GlossSeeAlso", 1},
+        {"glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossSee"},
+    }
+    for _, path := range paths {
+        expected, _ := Q(j, path...)
+        raw, err := QBytes(data, path...)
+        if err != nil || !equal(loadJSON(string(raw), t), expected) {
+            t.Errorf("path %s: %s, %v", FormatPath(path...), raw, err)
+        }
+    }
+    raw, err := QBytes(data, "glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossDef", "GlossSeeAlso")
+    if err != nil || string(raw) != `["GML", "XML"]` {
+        t.Fail()
+    }
+    raw, err = QBytes([]byte(`{"ab": [ 1 , {"c" :-1.5e3} ]}`), "ab", 1, "c")
+    if err != nil || string(raw) != `-1.5e3` {
+        t.Fail()
+    }
+}
+
+func TestQBytesScalars(t *testing.T) {
+    data := []byte(`{"s": "plain", "e": "line\nbreak é", "n": 12.5, "i": 9007199254740993,
+        "t": true, "f": false, "z": null, "o": {}}`)
+    if v, err := QBytesString(data, "s"); err != nil || v != "plain" {
+        t.Fail()
+    }
+    if v, err := QBytesString(data, "e"); err != nil || v != "line\nbreak é" {
+        t.Fail()
+    }
+    if v, err := QBytesNumber(data, "n"); err != nil || v != 12.5 {
+        t.Fail()
+    }
+    if v, err := QBytesInt64(data, "i"); err != nil || v != 9007199254740993 {
+        t.Fail()
+    }
+    if v, err := QBytesBool(data, "t"); err != nil || !v {
+        t.Fail()
+    }
+    if v, err := QBytesBool(data, "f"); err != nil || v {
+        t.Fail()
+    }
+    if err := QBytesNull(data, "z"); err != nil {
+        t.Fail()
+    }
+
+    var terr TypeError
+    _, err := QBytesString(data, "n")
+    if !errors.As(err, &terr) || terr.Kind() != "number" || terr.Depth() != 1 {
+        t.Fail()
+    }
+    if _, err = QBytesNumber(data, "o"); !errors.As(err, &terr) || terr.Kind() != "object" {
+        t.Fail()
+    }
+    if _, err = QBytesInt64(data, "n"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err = QBytesInt64([]byte(`1e30`)); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err = QBytesBool(data, "z"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if err = QBytesNull(data, "f"); !errors.As(err, &terr) || terr.Kind() != "boolean" {
+        t.Fail()
+    }
+    if _, err = QBytesString(data, "missing"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+}
+
+func TestQBytesErrors(t *testing.T) {
+    data := []byte(EXAMPLE2)
+    var kerr KeyError
+    _, err := QBytes(data, "menu", "popup", "missing")
+    if !errors.As(err, &kerr) || kerr.Depth() != 2 || kerr.Kind() != "object" ||
+        FormatPath(kerr.Path()...) != "menu.popup.missing" {
+        t.Fail()
+    }
+    var ierr IndexError
+    _, err = QBytes(data, "menu", "popup", "menuitem", 3)
+    if !errors.As(err, &ierr) || ierr.Depth() != 3 || ierr.Kind() != "array" {
+        t.Fail()
+    }
+    if _, err = QBytes([]byte(`[]`), 0); !errors.Is(err, ErrIndexOutOfRange) {
+        t.Fail()
+    }
+    if _, err = QBytes([]byte(`{}`), "a"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+    if _, err = QBytes(data, "menu", "popup", "menuitem", -1); !errors.Is(err, ErrIndexOutOfRange) {
+        t.Fail()
+    }
+    var terr TypeError
+    _, err = QBytes(data, "menu", "id", 0)
+    if !errors.As(err, &terr) || terr.Depth() != 2 || terr.Kind() != "string" {
+        t.Fail()
+    }
+    if _, err = QBytes(data, "menu", 0); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    deep := []byte(strings.Repeat("[", 1000000))
+    if _, err = QBytes(deep, 0); !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    deep = []byte(strings.Repeat("[", 5000) + strings.Repeat("]", 5000))
+    if _, err = QBytes(deep, 0); err != nil {
+        t.Fail()
+    }
+    if _, err = QBytes(data, Descend); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+
+    var perr ParseError
+    bad := []string{``, `{`, `{"a" 1}`, `{"a": tru}`, `[1,]`, `{"a": "\x"}`, `{"a": 01}`, `{"a": -}`,
+        `{"a": 1.}`, `{"a": "` + "\x01" + `"}`, `{"a": "open`, `{"b": [1 2], "a": 1}`, `{"a": 1 "b"}`}
+    for _, input := range bad {
+        if _, err = QBytes([]byte(input), "a"); !errors.As(err, &perr) || !errors.Is(err, ErrSyntax) {
+            t.Errorf("input %q: %v", input, err)
+        }
+    }
+    _, err = QBytes([]byte(`{"a": {"b": [1, x]}}`), "a", "b", 1)
+    if !errors.As(err, &perr) || perr.Offset() != 16 || FormatPath(perr.Path()...) != "a.b[1]" {
+        t.Fail()
+    }
+    if !strings.Contains(err.Error(), "'x'") {
+        t.Fail()
+    }
+}
@@ -0,0 +1,317 @@
+package qjson
+
+import (
+    "encoding"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "strconv"
+)
+
+var (
+    jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+    textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+    jsonNumberType      = reflect.TypeOf(json.Number(""))
+)
+
+// Same as Q(), but decodes retrieved subtree into value pointed by dst.
+// Decoding follows rules of json.Unmarshal(): struct fields are matched by
+// `json` tags or names, maps, slices and pointers are allocated as needed and
+// types implementing json.Unmarshaler or encoding.TextUnmarshaler decode
+// themselves. Subtree is not encoded to JSON unless dst contains
+// json.Unmarshaler. Errors carry full path to the field which failed.
+func QInto(V interface{}, dst interface{}, keys ...interface{}) error {
+    rv := reflect.ValueOf(dst)
+    if rv.Kind() != reflect.Ptr || rv.IsNil() {
+        return newArgError("Destination must be a non-nil pointer")
+    }
+    val, err := Q(V, keys...)
+    if err != nil {
+        return err
+    }
+    path := make([]interface{}, len(keys), len(keys)+8)
+    copy(path, keys)
+    return decode(val, rv.Elem(), path, false)
+}
+
+func decodeError(src interface{}, dst reflect.Value, path []interface{}) error {
+    return locate(newTypeError(fmt.Sprintf("Can't decode %s into %v", kindOf(src), dst.Type())),
+        path, len(path), src)
+}
+
+// Returns unmarshaler implemented by dst or its address.
+func unmarshalers(dst reflect.Value) (json.Unmarshaler, encoding.TextUnmarshaler) {
+    if dst.Kind() != reflect.Ptr && dst.CanAddr() {
+        dst = dst.Addr()
+    }
+    if dst.Kind() != reflect.Ptr || dst.Type().NumMethod() == 0 {
+        return nil, nil
+    }
+    if dst.IsNil() {
+        if !dst.CanSet() {
+            return nil, nil
+        }
+        dst.Set(reflect.New(dst.Type().Elem()))
+    }
+    if u, ok := dst.Interface().(json.Unmarshaler); ok {
+        return u, nil
+    }
+    if u, ok := dst.Interface().(encoding.TextUnmarshaler); ok {
+        return nil, u
+    }
+    return nil, nil
+}
+
+func decode(src interface{}, dst reflect.Value, path []interface{}, quoted bool) error {
+    if isNull(src) {
+        switch dst.Kind() {
+        case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+            dst.Set(reflect.Zero(dst.Type()))
+            return nil
+        }
+        // Like json.Unmarshal(), null is no-op for other types unless they
+        // decode themselves.
+        if ju, _ := unmarshalers(dst); ju != nil {
+            if err := ju.UnmarshalJSON([]byte("null")); err != nil {
+                return locate(newTypeError(err.Error()), path, len(path), src)
+            }
+        }
+        return nil
+    }
+    if dst.Kind() != reflect.Interface || dst.NumMethod() > 0 {
+        ju, tu := unmarshalers(dst)
+        if ju != nil {
+            data, err := json.Marshal(src)
+            if err == nil {
+                err = ju.UnmarshalJSON(data)
+            }
+            if err != nil {
+                return locate(newTypeError(err.Error()), path, len(path), src)
+            }
+            return nil
+        }
+        if tu != nil {
+            s, ok := src.(string)
+            if !ok {
+                return decodeError(src, dst, path)
+            }
+            if err := tu.UnmarshalText([]byte(s)); err != nil {
+                return locate(newTypeError(err.Error()), path, len(path), src)
+            }
+            return nil
+        }
+    }
+    if quoted {
+        // Value encoded as string because of ",string" tag option
+        s, ok := src.(string)
+        if !ok {
+            return decodeError(src, dst, path)
+        }
+        switch dst.Kind() {
+        case reflect.String:
+        case reflect.Bool:
+            b, err := strconv.ParseBool(s)
+            if err != nil {
+                return decodeError(src, dst, path)
+            }
+            src = b
+        default:
+            if _, err := strconv.ParseFloat(s, 64); err != nil {
+                return decodeError(src, dst, path)
+            }
+            src = json.Number(s)
+        }
+    }
+
+    if o, ok := src.(*Object); ok && dst.Kind() != reflect.Interface && dst.Kind() != reflect.Ptr {
+        src = o.Map()
+    }
+    switch dst.Kind() {
+    case reflect.Ptr:
+        if dst.IsNil() {
+            dst.Set(reflect.New(dst.Type().Elem()))
+        }
+        return decode(src, dst.Elem(), path, false)
+    case reflect.Interface:
+        if dst.NumMethod() > 0 {
+            return decodeError(src, dst, path)
+        }
+        dst.Set(reflect.ValueOf(deepCopy(src)))
+        return nil
+    case reflect.Bool:
+        b, ok := src.(bool)
+        if !ok {
+            return decodeError(src, dst, path)
+        }
+        dst.SetBool(b)
+        return nil
+    case reflect.String:
+        if dst.Type() == jsonNumberType {
+            n, err := toJSONNumber(src)
+            if err != nil {
+                return locate(err, path, len(path), src)
+            }
+            dst.SetString(string(n))
+            return nil
+        }
+        s, ok := src.(string)
+        if !ok {
+            return decodeError(src, dst, path)
+        }
+        dst.SetString(s)
+        return nil
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        if !isNumber(src) {
+            return decodeError(src, dst, path)
+        }
+        i, err := toInt64(src)
+        if err == nil && dst.OverflowInt(i) {
+            err = newRangeError(fmt.Sprintf("Value %d is out of %v range", i, dst.Type()))
+        }
+        if err != nil {
+            return locate(err, path, len(path), src)
+        }
+        dst.SetInt(i)
+        return nil
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        if !isNumber(src) {
+            return decodeError(src, dst, path)
+        }
+        u, err := toUint64(src)
+        if err == nil && dst.OverflowUint(u) {
+            err = newRangeError(fmt.Sprintf("Value %d is out of %v range", u, dst.Type()))
+        }
+        if err != nil {
+            return locate(err, path, len(path), src)
+        }
+        dst.SetUint(u)
+        return nil
+    case reflect.Float32, reflect.Float64:
+        if !isNumber(src) {
+            return decodeError(src, dst, path)
+        }
+        f, err := toFloat64(src)
+        if err == nil && dst.OverflowFloat(f) {
+            err = newRangeError(fmt.Sprintf("Value %v is out of %v range", f, dst.Type()))
+        }
+        if err != nil {
+            return locate(err, path, len(path), src)
+        }
+        dst.SetFloat(f)
+        return nil
+    case reflect.Slice:
+        if s, ok := src.(string); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+            b, err := base64.StdEncoding.DecodeString(s)
+            if err != nil {
+                return locate(newTypeError(err.Error()), path, len(path), src)
+            }
+            dst.SetBytes(b)
+            return nil
+        }
+        a, ok := src.([]interface{})
+        if !ok {
+            return decodeError(src, dst, path)
+        }
+        res := reflect.MakeSlice(dst.Type(), len(a), len(a))
+        for i, elem := range a {
+            if err := decode(elem, res.Index(i), append(path, i), false); err != nil {
+                return err
+            }
+        }
+        dst.Set(res)
+        return nil
+    case reflect.Array:
+        a, ok := src.([]interface{})
+        if !ok {
+            return decodeError(src, dst, path)
+        }
+        for i := 0; i < dst.Len(); i++ {
+            if i >= len(a) {
+                dst.Index(i).Set(reflect.Zero(dst.Type().Elem()))
+                continue
+            }
+            if err := decode(a[i], dst.Index(i), append(path, i), false); err != nil {
+                return err
+            }
+        }
+        return nil
+    case reflect.Map:
+        return decodeMap(src, dst, path)
+    case reflect.Struct:
+        m, ok := src.(map[string]interface{})
+        if !ok {
+            return decodeError(src, dst, path)
+        }
+        fields := structFields(dst.Type())
+        for _, k := range sortedKeys(m) {
+            f := lookupField(fields, k, true)
+            if f == nil {
+                continue
+            }
+            fv := fieldByIndex(dst, f.index, true)
+            if !fv.IsValid() {
+                return locate(newTypeError(fmt.Sprintf("Can't set embedded field of unexported struct type for key %q", k)),
+                    append(path, k), len(path), m)
+            }
+            quoted := f.quoted
+            switch f.typ.Kind() {
+            case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+                reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+                reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+            default:
+                quoted = false
+            }
+            if quoted && m[k] == nil {
+                continue
+            }
+            if err := decode(m[k], fv, append(path, k), quoted); err != nil {
+                return err
+            }
+        }
+        return nil
+    default:
+        return decodeError(src, dst, path)
+    }
+}
+
+func decodeMap(src interface{}, dst reflect.Value, path []interface{}) error {
+    m, ok := src.(map[string]interface{})
+    if !ok {
+        return decodeError(src, dst, path)
+    }
+    typ := dst.Type()
+    kt := typ.Key()
+    switch kt.Kind() {
+    case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+    default:
+        if !reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+            return decodeError(src, dst, path)
+        }
+    }
+    if dst.IsNil() {
+        dst.Set(reflect.MakeMapWithSize(typ, len(m)))
+    }
+    for _, k := range sortedKeys(m) {
+        elem := reflect.New(typ.Elem()).Elem()
+        if err := decode(m[k], elem, append(path, k), false); err != nil {
+            return err
+        }
+        key := reflect.New(kt).Elem()
+        var err error
+        switch {
+        case reflect.PtrTo(kt).Implements(textUnmarshalerType):
+            err = key.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(k))
+        case kt.Kind() == reflect.String:
+            key.SetString(k)
+        default:
+            err = decode(json.Number(k), key, append(path, k), false)
+        }
+        if err != nil {
+            return locate(newTypeError(fmt.Sprintf("Bad map key %q for %v", k, typ)), append(path, k), len(path), m)
+        }
+        dst.SetMapIndex(key, elem)
+    }
+    return nil
+}
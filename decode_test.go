@@ -0,0 +1,141 @@
+package qjson
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+)
+
+type decodeBase struct {
+    ID      int    `json:"id"`
+    Comment string `json:"comment,omitempty"`
+}
+
+type decodeRecord struct {
+    decodeBase
+    Name    string            `json:"name"`
+    Count   int64             `json:"count,string"`
+    Tags    []string          `json:"tags"`
+    Pair    [2]float64        `json:"pair"`
+    Scores  map[int]uint8     `json:"scores"`
+    Blob    []byte            `json:"blob"`
+    When    time.Time         `json:"when"`
+    Extra   interface{}       `json:"extra"`
+    Parent  *decodeBase       `json:"parent"`
+    Labels  map[string]string `json:"labels"`
+    Ignored string            `json:"-"`
+    Untagged bool
+}
+
+const DECODE_RECORD = `{
+    "id": 7,
+    "name": "seven",
+    "count": "42",
+    "tags": ["a", "b"],
+    "pair": [1.5, 2.5, 3.5],
+    "scores": {"1": 10, "2": 20},
+    "blob": "aGVsbG8=",
+    "when": "2020-01-02T03:04:05Z",
+    "extra": {"x": [1, 2]},
+    "parent": {"id": 1, "comment": "root"},
+    "labels": null,
+    "Ignored": "nope",
+    "UNTAGGED": true,
+    "unknown": 1
+}`
+
+func TestQIntoStruct(t *testing.T) {
+    j := loadJSON(`{"records": [` + DECODE_RECORD + `]}`, t)
+    var r decodeRecord
+    r.Labels = map[string]string{"stale": "yes"}
+    err := QInto(j, &r, "records", 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if r.ID != 7 || r.Name != "seven" || r.Count != 42 || !r.Untagged || r.Ignored != "" {
+        t.Fail()
+    }
+    if !reflect.DeepEqual(r.Tags, []string{"a", "b"}) || r.Pair != [2]float64{1.5, 2.5} {
+        t.Fail()
+    }
+    if !reflect.DeepEqual(r.Scores, map[int]uint8{1: 10, 2: 20}) || string(r.Blob) != "hello" {
+        t.Fail()
+    }
+    if !r.When.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+        t.Fail()
+    }
+    if r.Parent == nil || *r.Parent != (decodeBase{1, "root"}) || r.Labels != nil {
+        t.Fail()
+    }
+    if dumpJSON(r.Extra, t) != `{"x":[1,2]}` {
+        t.Fail()
+    }
+    // Decoded value must not share containers with source document
+    r.Extra.(map[string]interface{})["x"] = nil
+    if v, _ := Q(j, "records", 0, "extra", "x", 1); v != 2.0 {
+        t.Fail()
+    }
+}
+
+func TestQIntoScalars(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    var s string
+    if err := QInto(j, &s, "menu", "popup", "menuitem", 2, "value"); err != nil || s != "Close" {
+        t.Fail()
+    }
+    var i int
+    if err := QInto(j, &i, "menu", "popup", "menuitem", 2, "value"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    var items []menuItem
+    if err := QInto(j, &items, "menu", "popup", "menuitem"); err != nil || len(items) != 3 || items[0].Value != "New" {
+        t.Fail()
+    }
+    if err := QInto(j, &items, "menu", "missing"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+}
+
+func TestQIntoErrors(t *testing.T) {
+    j := loadJSON(DECODE_RECORD, t)
+    var r decodeRecord
+    if err := QInto(j, r); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+    if err := QInto(j, (*decodeRecord)(nil)); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+
+    U(&j, "tags", 1, 5)
+    err := QInto(j, &r)
+    var terr TypeError
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "tags[1]" || terr.Kind() != "number" {
+        t.Fail()
+    }
+
+    U(&j, "tags", 1, "b")
+    U(&j, "scores", "3", 300)
+    err = QInto(j, &r)
+    var rerr RangeError
+    if !errors.As(err, &rerr) || FormatPath(rerr.Path()...) != "scores.3" || rerr.Depth() != 2 {
+        t.Fail()
+    }
+
+    U(&j, "scores", map[string]interface{}{"x": 1})
+    if err = QInto(j, &r); !errors.As(err, &terr) || FormatPath(terr.Path()...) != "scores.x" {
+        t.Fail()
+    }
+
+    U(&j, "scores", nil)
+    U(&j, "count", 42)
+    if err = QInto(j, &r); !errors.As(err, &terr) || FormatPath(terr.Path()...) != "count" {
+        t.Fail()
+    }
+
+    U(&j, "count", "42")
+    U(&j, "when", "yesterday")
+    if err = QInto(j, &r); !errors.As(err, &terr) || FormatPath(terr.Path()...) != "when" {
+        t.Fail()
+    }
+}
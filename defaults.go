@@ -0,0 +1,75 @@
+package qjson
+
+import (
+    "errors"
+)
+
+// Reports if error means that requested path does not exist in document.
+func isAbsent(err error) bool {
+    return errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrIndexOutOfRange)
+}
+
+// Same as QBool(), but returns def if path does not exist. Other errors,
+// like TypeError on value of wrong type, are returned along with def.
+func QBoolOr(V interface{}, def bool, keys ...interface{}) (bool, error) {
+    res, err := QBool(V, keys...)
+    if err != nil {
+        if isAbsent(err) {
+            return def, nil
+        }
+        return def, err
+    }
+    return res, nil
+}
+
+// Same as QNumber(), but returns def if path does not exist. Other errors,
+// like TypeError on value of wrong type, are returned along with def.
+func QNumberOr(V interface{}, def float64, keys ...interface{}) (float64, error) {
+    res, err := QNumber(V, keys...)
+    if err != nil {
+        if isAbsent(err) {
+            return def, nil
+        }
+        return def, err
+    }
+    return res, nil
+}
+
+// Same as QString(), but returns def if path does not exist. Other errors,
+// like TypeError on value of wrong type, are returned along with def.
+func QStringOr(V interface{}, def string, keys ...interface{}) (string, error) {
+    res, err := QString(V, keys...)
+    if err != nil {
+        if isAbsent(err) {
+            return def, nil
+        }
+        return def, err
+    }
+    return res, nil
+}
+
+// Same as QList(), but returns def if path does not exist. Other errors,
+// like TypeError on value of wrong type, are returned along with def.
+func QListOr(V interface{}, def []interface{}, keys ...interface{}) ([]interface{}, error) {
+    res, err := QList(V, keys...)
+    if err != nil {
+        if isAbsent(err) {
+            return def, nil
+        }
+        return def, err
+    }
+    return res, nil
+}
+
+// Same as QObject(), but returns def if path does not exist. Other errors,
+// like TypeError on value of wrong type, are returned along with def.
+func QObjectOr(V interface{}, def map[string]interface{}, keys ...interface{}) (map[string]interface{}, error) {
+    res, err := QObject(V, keys...)
+    if err != nil {
+        if isAbsent(err) {
+            return def, nil
+        }
+        return def, err
+    }
+    return res, nil
+}
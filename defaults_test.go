@@ -0,0 +1,89 @@
+package qjson
+
+import (
+    "errors"
+    "testing"
+)
+
+var CONFIG = `
+{
+    "server": {
+        "host": "localhost",
+        "port": 8080,
+        "tls": true,
+        "aliases": ["a", "b"],
+        "limits": {"rps": 10},
+        "timeout": "30s"
+    },
+    "nothing": null
+}
+`
+
+func TestOr(t *testing.T) {
+    j := loadJSON(CONFIG, t)
+    s, err := QStringOr(j, "0.0.0.0", "server", "bind")
+    if err != nil || s != "0.0.0.0" {
+        t.Fail()
+    }
+    s, err = QStringOr(j, "0.0.0.0", "server", "host")
+    if err != nil || s != "localhost" {
+        t.Fail()
+    }
+    s, err = QStringOr(j, "x", "server", "port")
+    if _, ok := err.(TypeError) ; !ok || s != "x" {
+        t.Fail()
+    }
+    n, err := QNumberOr(j, 10, "server", "timeout")
+    if _, ok := err.(TypeError) ; !ok || n != 10 {
+        t.Fail()
+    }
+    n, err = QNumberOr(j, 10, "server", "retries")
+    if err != nil || n != 10 {
+        t.Fail()
+    }
+    b, err := QBoolOr(j, false, "server", "host")
+    if _, ok := err.(TypeError) ; !ok || b {
+        t.Fail()
+    }
+    b, err = QBoolOr(j, true, "server", "debug")
+    if err != nil || !b {
+        t.Fail()
+    }
+    l, err := QListOr(j, nil, "server", "aliases", 5)
+    if err != nil || l != nil {
+        t.Fail()
+    }
+    _, err = QListOr(j, nil, "server", "limits")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    o, err := QObjectOr(j, nil, "server", "limits")
+    if err != nil || len(o) != 1 {
+        t.Fail()
+    }
+    _, err = QObjectOr(j, nil, "nothing", "limits")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = QObjectOr(j, nil, "server", .0)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    l, err = QListOr(j, nil, "server", "aliases")
+    if err != nil || len(l) != 2 {
+        t.Fail()
+    }
+    o, err = QObjectOr(j, nil, "missing")
+    if err != nil || o != nil {
+        t.Fail()
+    }
+    // Values of wrong type are reported, not replaced with default
+    s, err = QStringOr(j, "d", "server", "aliases")
+    if !errors.Is(err, ErrTypeMismatch) || s != "d" {
+        t.Fail()
+    }
+    _, err = QStringOr(j, "d", "server", Any)
+    if !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+}
@@ -0,0 +1,212 @@
+package qjson
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Compares two JSON documents and returns RFC 6902 JSON Patch which
+// transforms a into b. Objects are compared key by key and arrays are
+// compared using longest common subsequence, so insertion or removal of
+// array element produces single operation. Arrays too large for that, with
+// product of lengths of their differing parts exceeding 1<<20, are replaced
+// as a whole.
+func Diff(a, b interface{}) Patch {
+    patch := Patch{}
+    diff(&patch, nil, a, b)
+    return patch
+}
+
+// Limit of LCS table size used by diffArrays().
+const maxDiffCells = 1 << 20
+
+func childPath(path []interface{}, key interface{}) []interface{} {
+    res := make([]interface{}, len(path)+1)
+    copy(res, path)
+    res[len(path)] = key
+    return res
+}
+
+func diff(patch *Patch, path []interface{}, a, b interface{}) {
+    if equal(a, b) {
+        return
+    }
+    if x, ok := asMap(a); ok {
+        if y, ok := asMap(b); ok {
+            diffObjects(patch, path, x, y)
+            return
+        }
+    }
+    switch x := a.(type) {
+    case []interface{}:
+        if y, ok := b.([]interface{}); ok {
+            diffArrays(patch, path, x, y)
+            return
+        }
+    }
+    *patch = append(*patch, Operation{Op: "replace", Path: FormatPointer(path...), Value: deepCopy(b)})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func diffObjects(patch *Patch, path []interface{}, a, b map[string]interface{}) {
+    for _, k := range sortedKeys(a) {
+        if _, ok := b[k]; !ok {
+            *patch = append(*patch, Operation{Op: "remove", Path: FormatPointer(childPath(path, k)...)})
+        }
+    }
+    for _, k := range sortedKeys(b) {
+        if av, ok := a[k]; ok {
+            diff(patch, childPath(path, k), av, b[k])
+        }
+    }
+    for _, k := range sortedKeys(b) {
+        if _, ok := a[k]; !ok {
+            *patch = append(*patch, Operation{Op: "add", Path: FormatPointer(childPath(path, k)...), Value: deepCopy(b[k])})
+        }
+    }
+}
+
+func diffArrays(patch *Patch, path []interface{}, a, b []interface{}) {
+    prefix := 0
+    for prefix < len(a) && prefix < len(b) && equal(a[prefix], b[prefix]) {
+        prefix++
+    }
+    suffix := 0
+    for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+        equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+        suffix++
+    }
+    x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+    if len(x) > 0 && len(y) > maxDiffCells/len(x) {
+        *patch = append(*patch, Operation{Op: "replace", Path: FormatPointer(path...), Value: deepCopy(b)})
+        return
+    }
+
+    // lcs[i][j] is length of longest common subsequence of x[i:] and y[j:]
+    lcs := make([][]int, len(x)+1)
+    for i := range lcs {
+        lcs[i] = make([]int, len(y)+1)
+    }
+    for i := len(x) - 1; i >= 0; i-- {
+        for j := len(y) - 1; j >= 0; j-- {
+            if equal(x[i], y[j]) {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else if lcs[i+1][j] >= lcs[i][j+1] {
+                lcs[i][j] = lcs[i+1][j]
+            } else {
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+
+    idx := prefix
+    var dels, ins []interface{}
+    // Pairs of removed and inserted elements between common elements are
+    // diffed in place, the rest becomes removals and additions.
+    flush := func() {
+        n := len(dels)
+        if len(ins) < n {
+            n = len(ins)
+        }
+        for k := 0; k < n; k++ {
+            diff(patch, childPath(path, idx), dels[k], ins[k])
+            idx++
+        }
+        for k := n; k < len(dels); k++ {
+            *patch = append(*patch, Operation{Op: "remove", Path: FormatPointer(childPath(path, idx)...)})
+        }
+        for k := n; k < len(ins); k++ {
+            *patch = append(*patch, Operation{Op: "add", Path: FormatPointer(childPath(path, idx)...), Value: deepCopy(ins[k])})
+            idx++
+        }
+        dels, ins = dels[:0], ins[:0]
+    }
+    i, j := 0, 0
+    for i < len(x) || j < len(y) {
+        switch {
+        case i < len(x) && j < len(y) && equal(x[i], y[j]):
+            flush()
+            idx++
+            i++
+            j++
+        case j >= len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+            dels = append(dels, x[i])
+            i++
+        default:
+            ins = append(ins, y[j])
+            j++
+        }
+    }
+    flush()
+}
+
+// Renders patch in human-readable form, one operation per line, with paths
+// in format of FormatPath(). Since JSON Pointers are untyped, patch is
+// replayed against document V to resolve them. Operations which can't be
+// applied are rendered as is.
+func FormatPatch(V interface{}, patch Patch) string {
+    var b strings.Builder
+    doc := deepCopy(V)
+    for i, op := range patch {
+        if i > 0 {
+            b.WriteByte('\n')
+        }
+        keys, err := PointerKeys(doc, op.Path)
+        if err != nil {
+            fmt.Fprintf(&b, "! %s %s", op.Op, op.Path)
+            continue
+        }
+        path := formatDiffPath(keys)
+        old, _ := Q(doc, keys...)
+        switch op.Op {
+        case "add":
+            fmt.Fprintf(&b, "+ %s: %s", path, formatDiffValue(op.Value))
+        case "remove":
+            fmt.Fprintf(&b, "- %s: %s", path, formatDiffValue(old))
+        case "replace":
+            fmt.Fprintf(&b, "~ %s: %s -> %s", path, formatDiffValue(old), formatDiffValue(op.Value))
+        case "move", "copy":
+            fromKeys, err := PointerKeys(doc, op.From)
+            if err != nil {
+                fmt.Fprintf(&b, "! %s %s", op.Op, op.Path)
+                continue
+            }
+            fmt.Fprintf(&b, "%s %s -> %s", map[string]string{"move": ">", "copy": "="}[op.Op],
+                formatDiffPath(fromKeys), path)
+        case "test":
+            fmt.Fprintf(&b, "? %s == %s", path, formatDiffValue(op.Value))
+        default:
+            fmt.Fprintf(&b, "! %s %s", op.Op, op.Path)
+            continue
+        }
+        if err := applyOperation(&doc, op); err != nil {
+            b.WriteString(" (failed)")
+        }
+    }
+    return b.String()
+}
+
+func formatDiffPath(keys []interface{}) string {
+    if len(keys) == 0 {
+        return "(root)"
+    }
+    return FormatPath(keys...)
+}
+
+func formatDiffValue(V interface{}) string {
+    res, err := json.Marshal(V)
+    if err != nil {
+        return fmt.Sprint(V)
+    }
+    return string(res)
+}
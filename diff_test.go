@@ -0,0 +1,117 @@
+package qjson
+
+import (
+    "testing"
+)
+
+func TestDiffRoundTrip(t *testing.T) {
+    cases := [][2]string{
+        {`{}`, `{}`},
+        {`{"a": 1}`, `{"a": 2}`},
+        {`{"a": 1, "b": 2}`, `{"b": 3, "c": 4}`},
+        {`{"a": {"b": [1, 2, 3]}}`, `{"a": {"b": [1, 3]}}`},
+        {`[1, 2, 3, 4, 5]`, `[0, 1, 3, 4, 6, 5, 7]`},
+        {`[1, 2, 3]`, `[]`},
+        {`[]`, `[1, 2, 3]`},
+        {`[{"a": 1}, {"b": 2}]`, `[{"a": 1}, {"b": 3}, {"c": 4}]`},
+        {`["a", "b", "c"]`, `["c", "b", "a"]`},
+        {`[1]`, `{"a": 1}`},
+        {`null`, `[null, {"a": null}]`},
+        {EXAMPLE, EXAMPLE2},
+    }
+    for _, c := range cases {
+        a, b := loadJSON(c[0], t), loadJSON(c[1], t)
+        ref := dumpJSON(a, t)
+        patch := Diff(a, b)
+        if dumpJSON(a, t) != ref {
+            t.Errorf("Diff(%s, %s) modified document", c[0], c[1])
+        }
+        if err := ApplyPatch(&a, patch); err != nil {
+            t.Errorf("Diff(%s, %s) produced bad patch %s: %v", c[0], c[1], dumpJSON(patch, t), err)
+            continue
+        }
+        if !equal(a, b) {
+            t.Errorf("Diff(%s, %s) = %s", c[0], c[1], dumpJSON(patch, t))
+        }
+    }
+}
+
+func TestDiffMinimal(t *testing.T) {
+    cases := []struct {
+        a, b, patch string
+    }{
+        {`[1, 2, 3]`, `[1, 2, 3]`, `[]`},
+        {`[1, 2, 3, 4]`, `[1, 2, 9, 3, 4]`, `[{"op":"add","path":"/2","value":9}]`},
+        {`[1, 2, 3, 4]`, `[1, 3, 4]`, `[{"op":"remove","path":"/1"}]`},
+        {`[{"id": 1, "v": "a"}, 5]`, `[{"id": 1, "v": "b"}, 5]`, `[{"op":"replace","path":"/0/v","value":"b"}]`},
+        {`{"a": {"b": 1, "c": 2}}`, `{"a": {"b": 1, "d": 2}}`, `[{"op":"remove","path":"/a/c"},{"op":"add","path":"/a/d","value":2}]`},
+        {`{"a/b": 1}`, `{"a/b": 2}`, `[{"op":"replace","path":"/a~1b","value":2}]`},
+        {`{"a": 1}`, `"x"`, `[{"op":"replace","path":"","value":"x"}]`},
+    }
+    for _, c := range cases {
+        patch := Diff(loadJSON(c.a, t), loadJSON(c.b, t))
+        if dumpJSON(patch, t) != c.patch {
+            t.Errorf("Diff(%s, %s) = %s", c.a, c.b, dumpJSON(patch, t))
+        }
+    }
+}
+
+func TestFormatPatch(t *testing.T) {
+    a := loadJSON(EXAMPLE2, t)
+    b := loadJSON(EXAMPLE2, t)
+    U(&b, "menu", "popup", "menuitem", 1, "value", "Reopen")
+    D(&b, "menu", "popup", "menuitem", 2)
+    U(&b, "menu", "a.b", true)
+    ref := `~ menu.popup.menuitem[1].value: "Open" -> "Reopen"
+- menu.popup.menuitem[2]: {"onclick":"CloseDoc()","value":"Close"}
++ menu["a.b"]: true`
+    if s := FormatPatch(a, Diff(a, b)); s != ref {
+        t.Error(s)
+    }
+    patch := Patch{
+        {Op: "move", From: "/menu/id", Path: "/id"},
+        {Op: "copy", From: "/id", Path: "/menu/id"},
+        {Op: "test", Path: "/id", Value: "file"},
+        {Op: "remove", Path: "/nonexistent"},
+        {Op: "remove", Path: "bad"},
+        {Op: "copy", From: "bad", Path: "/x"},
+        {Op: "frobnicate", Path: "/x"},
+        {Op: "replace", Path: "", Value: nil},
+    }
+    ref = `> menu.id -> id
+= id -> menu.id
+? id == "file"
+- nonexistent: null (failed)
+! remove bad
+! copy /x
+! frobnicate /x
+~ (root): {"id":"file","menu":{"id":"file","popup":{"menuitem":[{"onclick":"CreateNewDoc()","value":"New"},{"onclick":"OpenDoc()","value":"Open"},{"onclick":"CloseDoc()","value":"Close"}]},"value":"File"}} -> null`
+    if s := FormatPatch(a, patch); s != ref {
+        t.Error(s)
+    }
+}
+
+func TestDiffLargeArrays(t *testing.T) {
+    x, y := make([]interface{}, 50000), make([]interface{}, 50000)
+    for i := range x {
+        x[i], y[i] = float64(i), float64(-i)
+    }
+    y[0] = "first"
+    var a, b interface{} = x, y
+    patch := Diff(a, b)
+    if len(patch) != 1 || patch[0].Op != "replace" || patch[0].Path != "" {
+        t.Fatalf("unexpected patch of %d operations", len(patch))
+    }
+    if err := ApplyPatch(&a, patch); err != nil || !equal(a, b) {
+        t.Fail()
+    }
+    // Small difference within large array is still found
+    U(&b, 25000, "middle")
+    patch = Diff(a, b)
+    if dumpJSON(patch, t) != `[{"op":"replace","path":"/25000","value":"middle"}]` {
+        t.Fail()
+    }
+    if len(FormatPatch(a, patch)) == 0 {
+        t.Fail()
+    }
+}
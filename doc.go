@@ -0,0 +1,134 @@
+package qjson
+
+import (
+    "encoding/json"
+)
+
+// JSON document. Doc owns root of JSON tree and provides methods built on
+// top of Q(), U() and D(), so caller doesn't have to keep track of when
+// root should be passed by pointer. Zero value is empty document holding
+// null.
+//
+// Doc returned by Sub() is scoped view of the same tree: its paths are
+// relative to the subtree, but changes made via view are visible in the
+// parent document and vice versa. Errors carry paths relative to root of
+// the whole document.
+type Doc struct {
+    root *interface{}
+    path []interface{}
+}
+
+// Creates document holding V. V is not copied.
+func NewDoc(V interface{}) *Doc {
+    return &Doc{root: &V}
+}
+
+func (d *Doc) rootPtr() *interface{} {
+    if d.root == nil {
+        d.root = new(interface{})
+    }
+    return d.root
+}
+
+func (d *Doc) fullPath(keys []interface{}) []interface{} {
+    if len(d.path) == 0 {
+        return keys
+    }
+    res := make([]interface{}, 0, len(d.path)+len(keys))
+    res = append(res, d.path...)
+    return append(res, keys...)
+}
+
+// Returns scoped view of subtree at given path. Subtree doesn't have to
+// exist: it is created by first Set() via view.
+func (d *Doc) Sub(keys ...interface{}) *Doc {
+    return &Doc{root: d.rootPtr(), path: d.fullPath(keys)}
+}
+
+// Returns path of view relative to root of the whole document.
+func (d *Doc) Path() []interface{} {
+    return append([]interface{}{}, d.path...)
+}
+
+// Returns value of document or view. Nil is returned if subtree of view
+// doesn't exist.
+func (d *Doc) Value() interface{} {
+    v, _ := d.Get()
+    return v
+}
+
+// Same as Q(), but relative to document.
+func (d *Doc) Get(keys ...interface{}) (interface{}, error) {
+    return Q(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as U(), but relative to document.
+// Invocation: Set(path... interface{}, newvalue interface{}).
+func (d *Doc) Set(keys ...interface{}) (interface{}, error) {
+    return U(d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as D(), but relative to document.
+func (d *Doc) Delete(keys ...interface{}) (interface{}, error) {
+    return D(d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QBool(), but relative to document.
+func (d *Doc) Bool(keys ...interface{}) (bool, error) {
+    return QBool(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QNumber(), but relative to document.
+func (d *Doc) Number(keys ...interface{}) (float64, error) {
+    return QNumber(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QInt(), but relative to document.
+func (d *Doc) Int(keys ...interface{}) (int, error) {
+    return QInt(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QString(), but relative to document. Not named String() to keep
+// Doc from being treated as fmt.Stringer.
+func (d *Doc) Str(keys ...interface{}) (string, error) {
+    return QString(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QList(), but relative to document.
+func (d *Doc) List(keys ...interface{}) ([]interface{}, error) {
+    return QList(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QObject(), but relative to document.
+func (d *Doc) Object(keys ...interface{}) (map[string]interface{}, error) {
+    return QObject(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QNull(), but relative to document.
+func (d *Doc) Null(keys ...interface{}) error {
+    return QNull(*d.rootPtr(), d.fullPath(keys)...)
+}
+
+// Same as QInto(), but relative to document.
+func (d *Doc) Into(dst interface{}, keys ...interface{}) error {
+    return QInto(*d.rootPtr(), dst, d.fullPath(keys)...)
+}
+
+// Encodes value of document or view. Missing subtree of view is encoded
+// as null.
+func (d Doc) MarshalJSON() ([]byte, error) {
+    if d.root == nil {
+        return []byte("null"), nil
+    }
+    return json.Marshal(d.Value())
+}
+
+// Replaces value of document or view with decoded data.
+func (d *Doc) UnmarshalJSON(data []byte) error {
+    var v interface{}
+    if err := json.Unmarshal(data, &v); err != nil {
+        return err
+    }
+    _, err := d.Set(v)
+    return err
+}
@@ -0,0 +1,133 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "testing"
+)
+
+func TestDocGetSet(t *testing.T) {
+    var d Doc
+    if d.Value() != nil {
+        t.Fail()
+    }
+    if _, err := d.Set("a", "b", 0, "c"); err != nil {
+        t.Fail()
+    }
+    if v, err := d.Str("a", "b"); !errors.Is(err, ErrTypeMismatch) || v != "" {
+        t.Fail()
+    }
+    if v, err := d.Get("a", "b", 0); err != nil || v != "c" {
+        t.Fail()
+    }
+    if _, err := d.Set("n", 42); err != nil {
+        t.Fail()
+    }
+    if v, err := d.Int("n"); err != nil || v != 42 {
+        t.Fail()
+    }
+    if v, err := d.Number("n"); err != nil || v != 42 {
+        t.Fail()
+    }
+    if old, err := d.Delete("n"); err != nil || old != 42 {
+        t.Fail()
+    }
+    if _, err := d.Get("n"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+    if _, err := d.Set(); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+}
+
+func TestDocSub(t *testing.T) {
+    d := NewDoc(loadJSON(EXAMPLE2, t))
+    items := d.Sub("menu", "popup", "menuitem")
+    if v, err := items.Str(1, "value"); err != nil || v != "Open" {
+        t.Fail()
+    }
+    second := items.Sub(1)
+    if _, err := second.Set("value", "Reopen"); err != nil {
+        t.Fail()
+    }
+    if v, _ := d.Str("menu", "popup", "menuitem", 1, "value"); v != "Reopen" {
+        t.Fail()
+    }
+    if FormatPath(second.Path()...) != "menu.popup.menuitem[1]" {
+        t.Fail()
+    }
+    var item menuItem
+    if err := second.Into(&item); err != nil || item.OnClick != "OpenDoc()" {
+        t.Fail()
+    }
+
+    // Errors carry full path
+    var kerr KeyError
+    _, err := second.Get("missing")
+    if !errors.As(err, &kerr) || FormatPath(kerr.Path()...) != "menu.popup.menuitem[1].missing" {
+        t.Fail()
+    }
+
+    // View of missing subtree creates it on first Set()
+    meta := d.Sub("menu", "meta")
+    if meta.Value() != nil {
+        t.Fail()
+    }
+    if _, err := meta.Set("author", "me"); err != nil {
+        t.Fail()
+    }
+    if v, _ := d.Str("menu", "meta", "author"); v != "me" {
+        t.Fail()
+    }
+    if _, err := meta.Delete(); err != nil {
+        t.Fail()
+    }
+    if _, err := d.Get("menu", "meta"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+
+    // Views of zero document share its root
+    var z Doc
+    z.Sub("a").Set("b", true)
+    if v, err := z.Bool("a", "b"); err != nil || !v {
+        t.Fail()
+    }
+}
+
+func TestDocJSON(t *testing.T) {
+    var d Doc
+    if dumpJSON(d, t) != "null" {
+        t.Fail()
+    }
+    err := json.Unmarshal([]byte(`{"a": {"b": [1, 2]}}`), &d)
+    if err != nil {
+        t.Fail()
+    }
+    if dumpJSON(d, t) != `{"a":{"b":[1,2]}}` || dumpJSON(d.Sub("a", "b"), t) != `[1,2]` {
+        t.Fail()
+    }
+    if dumpJSON(d.Sub("x"), t) != "null" {
+        t.Fail()
+    }
+    if err = json.Unmarshal([]byte(`{"c": null}`), d.Sub("a", "b", 1)); err != nil {
+        t.Fail()
+    }
+    if dumpJSON(&d, t) != `{"a":{"b":[1,{"c":null}]}}` {
+        t.Fail()
+    }
+
+    // Doc embedded into other types
+    var wrapper struct {
+        Doc  *Doc `json:"doc"`
+        Name string `json:"name"`
+    }
+    if err = json.Unmarshal([]byte(`{"doc": [true], "name": "w"}`), &wrapper); err != nil {
+        t.Fail()
+    }
+    if v, err := wrapper.Doc.Bool(0); err != nil || !v {
+        t.Fail()
+    }
+    if err = json.Unmarshal([]byte(`{`), &d); err == nil {
+        t.Fail()
+    }
+}
@@ -0,0 +1,181 @@
+package qjson
+
+import (
+    "reflect"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// Struct field visible to JSON under name derived from `json` tag.
+type structField struct {
+    name      string
+    tagged    bool
+    index     []int
+    typ       reflect.Type
+    omitEmpty bool
+    quoted    bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]structField
+
+// Returns fields of struct type t visible to JSON according to the same
+// rules encoding/json uses: `json` tags rename or hide fields, fields of
+// embedded structs are promoted and shallower or tagged fields win over
+// conflicting ones.
+func structFields(t reflect.Type) []structField {
+    if f, ok := fieldCache.Load(t); ok {
+        return f.([]structField)
+    }
+    f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+    return f.([]structField)
+}
+
+func typeFields(t reflect.Type) []structField {
+    var fields []structField
+    current := []structField{}
+    next := []structField{{typ: t}}
+    count := map[reflect.Type]int{}
+    nextCount := map[reflect.Type]int{}
+    visited := map[reflect.Type]bool{}
+    for len(next) > 0 {
+        current, next = next, current[:0]
+        count, nextCount = nextCount, map[reflect.Type]int{}
+        for _, f := range current {
+            if visited[f.typ] {
+                continue
+            }
+            visited[f.typ] = true
+            for i := 0; i < f.typ.NumField(); i++ {
+                sf := f.typ.Field(i)
+                ft := sf.Type
+                if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+                    ft = ft.Elem()
+                }
+                if sf.Anonymous {
+                    if !sf.IsExported() && ft.Kind() != reflect.Struct {
+                        continue
+                    }
+                } else if !sf.IsExported() {
+                    continue
+                }
+                tag := sf.Tag.Get("json")
+                if tag == "-" {
+                    continue
+                }
+                name, opts := tag, ""
+                if i := strings.IndexByte(tag, ','); i >= 0 {
+                    name, opts = tag[:i], tag[i:]
+                }
+                index := make([]int, len(f.index)+1)
+                copy(index, f.index)
+                index[len(f.index)] = i
+                if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+                    field := structField{
+                        name:      name,
+                        tagged:    name != "",
+                        index:     index,
+                        typ:       sf.Type,
+                        omitEmpty: strings.Contains(opts, ",omitempty"),
+                        quoted:    strings.Contains(opts, ",string"),
+                    }
+                    if field.name == "" {
+                        field.name = sf.Name
+                    }
+                    fields = append(fields, field)
+                    if count[f.typ] > 1 {
+                        // Same embedded type appears twice at this depth,
+                        // so its fields annihilate each other.
+                        fields = append(fields, field)
+                    }
+                    continue
+                }
+                nextCount[ft]++
+                if nextCount[ft] == 1 {
+                    next = append(next, structField{name: ft.Name(), index: index, typ: ft})
+                }
+            }
+        }
+    }
+
+    sort.Slice(fields, func(i, j int) bool {
+        x, y := fields[i], fields[j]
+        if x.name != y.name {
+            return x.name < y.name
+        }
+        if len(x.index) != len(y.index) {
+            return len(x.index) < len(y.index)
+        }
+        if x.tagged != y.tagged {
+            return x.tagged
+        }
+        return lessIndex(x.index, y.index)
+    })
+    res := fields[:0]
+    for i := 0; i < len(fields); {
+        j := i + 1
+        for j < len(fields) && fields[j].name == fields[i].name {
+            j++
+        }
+        // Fields are sorted by depth and tagging, so the first one
+        // dominates unless the second one is equally good.
+        if j-i == 1 || len(fields[i].index) < len(fields[i+1].index) ||
+            fields[i].tagged && !fields[i+1].tagged {
+            res = append(res, fields[i])
+        }
+        i = j
+    }
+    sort.Slice(res, func(i, j int) bool {
+        return lessIndex(res[i].index, res[j].index)
+    })
+    return res
+}
+
+func lessIndex(a, b []int) bool {
+    for k := range a {
+        if k >= len(b) {
+            return false
+        }
+        if a[k] != b[k] {
+            return a[k] < b[k]
+        }
+    }
+    return len(a) < len(b)
+}
+
+// Finds field by JSON name. Exact match is preferred, otherwise, if fold is
+// set, name is matched case-insensitively like encoding/json does.
+func lookupField(fields []structField, name string, fold bool) *structField {
+    for i := range fields {
+        if fields[i].name == name {
+            return &fields[i]
+        }
+    }
+    if !fold {
+        return nil
+    }
+    for i := range fields {
+        if strings.EqualFold(fields[i].name, name) {
+            return &fields[i]
+        }
+    }
+    return nil
+}
+
+// Returns struct field by index, allocating nil embedded pointers on the
+// way if alloc is set. Returns invalid value if field can't be reached.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+    for i, x := range index {
+        if i > 0 && v.Kind() == reflect.Ptr {
+            if v.IsNil() {
+                if !alloc || !v.CanSet() {
+                    return reflect.Value{}
+                }
+                v.Set(reflect.New(v.Type().Elem()))
+            }
+            v = v.Elem()
+        }
+        v = v.Field(x)
+    }
+    return v
+}
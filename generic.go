@@ -0,0 +1,43 @@
+package qjson
+
+import (
+    "fmt"
+    "reflect"
+)
+
+// Same as Q(), but converts retrieved value to type T. Value of type T is
+// returned as is, numbers are converted to any numeric type T with range and
+// precision checks, other types are decoded from retrieved subtree in the
+// same way as QInto() does. If conversion failed TypeError or RangeError is
+// returned.
+func QAs[T any](V interface{}, keys ...interface{}) (T, error) {
+    var zero T
+    val, err := Q(V, keys...)
+    if err != nil {
+        return zero, err
+    }
+    res, err := as[T](val, keys)
+    if err != nil {
+        return zero, err
+    }
+    return res, nil
+}
+
+func as[T any](V interface{}, path []interface{}) (T, error) {
+    var res T
+    if v, ok := V.(T); ok {
+        return v, nil
+    }
+    typ := reflect.TypeOf(&res).Elem()
+    if V == nil {
+        switch typ.Kind() {
+        case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+            return res, nil
+        }
+        return res, locate(newTypeError(fmt.Sprintf("Retrieved null can't be converted to %v", typ)),
+            path, len(path), V)
+    }
+    p := make([]interface{}, len(path), len(path)+8)
+    copy(p, path)
+    return res, decode(V, reflect.ValueOf(&res).Elem(), p, false)
+}
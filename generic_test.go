@@ -0,0 +1,142 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+)
+
+type menuItem struct {
+    Value   string `json:"value"`
+    OnClick string `json:"onclick"`
+}
+
+func TestQAsJSONKinds(t *testing.T) {
+    j := loadJSON(CONFIG, t)
+    host, err := QAs[string](j, "server", "host")
+    if err != nil || host != "localhost" {
+        t.Fail()
+    }
+    tls, err := QAs[bool](j, "server", "tls")
+    if err != nil || !tls {
+        t.Fail()
+    }
+    aliases, err := QAs[[]interface{}](j, "server", "aliases")
+    if err != nil || len(aliases) != 2 {
+        t.Fail()
+    }
+    limits, err := QAs[map[string]interface{}](j, "server", "limits")
+    if err != nil || len(limits) != 1 {
+        t.Fail()
+    }
+    v, err := QAs[interface{}](j, "nothing")
+    if err != nil || v != nil {
+        t.Fail()
+    }
+    _, err = QAs[string](j, "server", "port")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = QAs[string](j, "nothing")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = QAs[bool](j, "server", "missing")
+    if !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+}
+
+func TestQAsNumbers(t *testing.T) {
+    j := loadJSON(CONFIG, t)
+    port, err := QAs[uint16](j, "server", "port")
+    if err != nil || port != 8080 {
+        t.Fail()
+    }
+    _, err = QAs[int8](j, "server", "port")
+    var rerr RangeError
+    if !errors.As(err, &rerr) || rerr.Depth() != 2 {
+        t.Fail()
+    }
+    f, err := QAs[float32](j, "server", "port")
+    if err != nil || f != 8080 {
+        t.Fail()
+    }
+    n, err := QAs[json.Number](j, "server", "port")
+    if err != nil || n != "8080" {
+        t.Fail()
+    }
+    d, err := QAs[time.Duration](j, "server", "limits", "rps")
+    if err != nil || d != 10 {
+        t.Fail()
+    }
+    _, err = QAs[int](j, "server", "host")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = QAs[uint](j, "server", "host")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = QAs[float64](j, "server", "host")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = QAs[json.Number](j, "server", "host")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = QAs[int](j, "nothing")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+
+    var k interface{}
+    U(&k, "big", json.Number("1e39"))
+    U(&k, "neg", -1)
+    U(&k, "frac", json.Number("0.5"))
+    if _, err = QAs[float32](k, "big"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err = QAs[uint](k, "neg"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err = QAs[int](k, "frac"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if v, err := QAs[int](k, "neg"); err != nil || v != -1 {
+        t.Fail()
+    }
+}
+
+func TestQAsUserTypes(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    item, err := QAs[menuItem](j, "menu", "popup", "menuitem", 1)
+    if err != nil || item != (menuItem{"Open", "OpenDoc()"}) {
+        t.Fail()
+    }
+    items, err := QAs[[]*menuItem](j, "menu", "popup", "menuitem")
+    if err != nil || len(items) != 3 || items[2].Value != "Close" {
+        t.Fail()
+    }
+    strs, err := QAs[map[string]string](j, "menu", "popup", "menuitem", 0)
+    if err != nil || !reflect.DeepEqual(strs, map[string]string{"value": "New", "onclick": "CreateNewDoc()"}) {
+        t.Fail()
+    }
+    ptr, err := QAs[*menuItem](loadJSON(`{"a": null}`, t), "a")
+    if err != nil || ptr != nil {
+        t.Fail()
+    }
+    _, err = QAs[[]string](j, "menu", "popup", "menuitem")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    var k interface{}
+    U(&k, "f", func() {})
+    _, err = QAs[menuItem](k, "f")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+}
@@ -0,0 +1,1010 @@
+package qjson
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+)
+
+// Single value found by multi-valued query along with concrete path to it.
+type Match struct {
+    Path  []interface{}
+    Value interface{}
+}
+
+// Returns path of match as RFC 9535 normalized path, e.g. `$['a'][0]`.
+func (m Match) NormalizedPath() string {
+    var b strings.Builder
+    b.WriteByte('$')
+    for _, key := range m.Path {
+        b.WriteByte('[')
+        switch k := key.(type) {
+        case string:
+            writeNormalizedString(&b, k)
+        default:
+            fmt.Fprint(&b, k)
+        }
+        b.WriteByte(']')
+    }
+    return b.String()
+}
+
+// Returns path of match as RFC 6901 JSON Pointer.
+func (m Match) Pointer() string {
+    return FormatPointer(m.Path...)
+}
+
+func writeNormalizedString(b *strings.Builder, s string) {
+    b.WriteByte('\'')
+    for _, c := range s {
+        switch c {
+        case '\'':
+            b.WriteString(`\'`)
+        case '\\':
+            b.WriteString(`\\`)
+        case '\b':
+            b.WriteString(`\b`)
+        case '\f':
+            b.WriteString(`\f`)
+        case '\n':
+            b.WriteString(`\n`)
+        case '\r':
+            b.WriteString(`\r`)
+        case '\t':
+            b.WriteString(`\t`)
+        default:
+            if c < 0x20 {
+                fmt.Fprintf(b, `\u%04x`, c)
+            } else {
+                b.WriteRune(c)
+            }
+        }
+    }
+    b.WriteByte('\'')
+}
+
+// Compiled RFC 9535 JSONPath expression.
+type JSONPath struct {
+    src      string
+    segments []jpSegment
+}
+
+// Parses RFC 9535 JSONPath expression, e.g. `$.menu.popup.menuitem[*].value`
+// or `$..book[?@.price < 10]`. Syntax errors are reported as ArgError.
+func CompileJSONPath(expr string) (*JSONPath, error) {
+    p := &jpParser{pathParser{src: expr}}
+    if !p.consume("$") {
+        return nil, p.errorf("expected '$'")
+    }
+    segments, err := p.segments()
+    if err != nil {
+        return nil, err
+    }
+    p.skipWS()
+    if p.pos < len(p.src) {
+        return nil, p.errorf("unexpected character %q", p.src[p.pos])
+    }
+    return &JSONPath{src: expr, segments: segments}, nil
+}
+
+// Same as CompileJSONPath(), but panics if expression can't be parsed.
+func MustCompileJSONPath(expr string) *JSONPath {
+    p, err := CompileJSONPath(expr)
+    if err != nil {
+        panic(err)
+    }
+    return p
+}
+
+// Returns source expression.
+func (p *JSONPath) String() string {
+    return p.src
+}
+
+// Evaluates JSONPath against document V and returns all matches in order
+// of their discovery. Object members are visited in order of sorted keys.
+func (p *JSONPath) Query(V interface{}) []Match {
+    nodes := jpEvalSegments(p.segments, V, jpNode{value: V})
+    res := make([]Match, len(nodes))
+    for i, n := range nodes {
+        res[i] = Match{Path: n.path, Value: n.value}
+    }
+    return res
+}
+
+// Evaluates RFC 9535 JSONPath expression against document V and returns all
+// matches.
+func QJSONPath(V interface{}, expr string) ([]Match, error) {
+    p, err := CompileJSONPath(expr)
+    if err != nil {
+        return nil, err
+    }
+    return p.Query(V), nil
+}
+
+type jpNode struct {
+    path  []interface{}
+    value interface{}
+}
+
+func (n jpNode) child(key, value interface{}) jpNode {
+    path := make([]interface{}, len(n.path)+1)
+    copy(path, n.path)
+    path[len(n.path)] = key
+    return jpNode{path: path, value: value}
+}
+
+// Iterates over children of array or object in document order.
+func jpChildren(n jpNode, fn func(jpNode)) {
+    switch v := n.value.(type) {
+    case []interface{}:
+        for i, elem := range v {
+            fn(n.child(i, elem))
+        }
+    case map[string]interface{}:
+        keys := make([]string, 0, len(v))
+        for k := range v {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        for _, k := range keys {
+            fn(n.child(k, v[k]))
+        }
+    case *Object:
+        for _, k := range v.Keys() {
+            fn(n.child(k, v.values[k]))
+        }
+    default:
+        if a, ok := nativeElems(v); ok {
+            for i, elem := range a {
+                fn(n.child(i, elem))
+            }
+        } else if keys, m, ok := nativeMembers(v); ok {
+            for _, k := range keys {
+                fn(n.child(k, m[k]))
+            }
+        }
+    }
+}
+
+// Returns elements of generic or native array.
+func jpArray(V interface{}) ([]interface{}, bool) {
+    if a, ok := V.([]interface{}); ok {
+        return a, true
+    }
+    return nativeElems(V)
+}
+
+type jpSegment struct {
+    descendant bool
+    selectors  []jpSelector
+}
+
+type jpSelector interface {
+    apply(root interface{}, n jpNode, out []jpNode) []jpNode
+}
+
+func jpEvalSegments(segments []jpSegment, root interface{}, start jpNode) []jpNode {
+    nodes := []jpNode{start}
+    for _, seg := range segments {
+        var next []jpNode
+        for _, n := range nodes {
+            if seg.descendant {
+                var visit func(jpNode)
+                visit = func(d jpNode) {
+                    for _, sel := range seg.selectors {
+                        next = sel.apply(root, d, next)
+                    }
+                    jpChildren(d, visit)
+                }
+                visit(n)
+            } else {
+                for _, sel := range seg.selectors {
+                    next = sel.apply(root, n, next)
+                }
+            }
+        }
+        nodes = next
+    }
+    return nodes
+}
+
+type jpNameSelector string
+
+func (s jpNameSelector) apply(root interface{}, n jpNode, out []jpNode) []jpNode {
+    if m, ok := asMap(n.value); ok {
+        if v, ok := m[string(s)]; ok {
+            out = append(out, n.child(string(s), v))
+        }
+    } else if _, m, ok := nativeMembers(n.value); ok {
+        if v, ok := m[string(s)]; ok {
+            out = append(out, n.child(string(s), v))
+        }
+    }
+    return out
+}
+
+type jpWildcardSelector struct{}
+
+func (jpWildcardSelector) apply(root interface{}, n jpNode, out []jpNode) []jpNode {
+    jpChildren(n, func(c jpNode) {
+        out = append(out, c)
+    })
+    return out
+}
+
+type jpIndexSelector int
+
+func (s jpIndexSelector) apply(root interface{}, n jpNode, out []jpNode) []jpNode {
+    if a, ok := jpArray(n.value); ok {
+        idx := int(s)
+        if idx < 0 {
+            idx += len(a)
+        }
+        if idx >= 0 && idx < len(a) {
+            out = append(out, n.child(idx, a[idx]))
+        }
+    }
+    return out
+}
+
+type jpSliceSelector struct {
+    start, end, step *int
+}
+
+func (s jpSliceSelector) apply(root interface{}, n jpNode, out []jpNode) []jpNode {
+    a, ok := jpArray(n.value)
+    if !ok {
+        return out
+    }
+    length := len(a)
+    step := 1
+    if s.step != nil {
+        step = *s.step
+    }
+    if step == 0 {
+        return out
+    }
+    normalize := func(i int) int {
+        if i < 0 {
+            return length + i
+        }
+        return i
+    }
+    clamp := func(i, lo, hi int) int {
+        if i < lo {
+            return lo
+        }
+        if i > hi {
+            return hi
+        }
+        return i
+    }
+    var start, end int
+    if step > 0 {
+        start, end = 0, length
+    } else {
+        start, end = length-1, -length-1
+    }
+    if s.start != nil {
+        start = *s.start
+    }
+    if s.end != nil {
+        end = *s.end
+    }
+    start, end = normalize(start), normalize(end)
+    if step > 0 {
+        lower, upper := clamp(start, 0, length), clamp(end, 0, length)
+        for i := lower; i < upper; i += step {
+            out = append(out, n.child(i, a[i]))
+        }
+    } else {
+        upper, lower := clamp(start, -1, length-1), clamp(end, -1, length-1)
+        for i := upper; lower < i; i += step {
+            out = append(out, n.child(i, a[i]))
+        }
+    }
+    return out
+}
+
+type jpFilterSelector struct {
+    expr jpLogicalExpr
+}
+
+func (s jpFilterSelector) apply(root interface{}, n jpNode, out []jpNode) []jpNode {
+    jpChildren(n, func(c jpNode) {
+        if s.expr.test(root, c.value) {
+            out = append(out, c)
+        }
+    })
+    return out
+}
+
+// Filter expressions
+
+type jpLogicalExpr interface {
+    test(root, cur interface{}) bool
+}
+
+type jpOr []jpLogicalExpr
+
+func (e jpOr) test(root, cur interface{}) bool {
+    for _, sub := range e {
+        if sub.test(root, cur) {
+            return true
+        }
+    }
+    return false
+}
+
+type jpAnd []jpLogicalExpr
+
+func (e jpAnd) test(root, cur interface{}) bool {
+    for _, sub := range e {
+        if !sub.test(root, cur) {
+            return false
+        }
+    }
+    return true
+}
+
+type jpNot struct {
+    expr jpLogicalExpr
+}
+
+func (e jpNot) test(root, cur interface{}) bool {
+    return !e.expr.test(root, cur)
+}
+
+// Existence test or test of logical function result.
+type jpTest struct {
+    operand jpOperand
+}
+
+func (e jpTest) test(root, cur interface{}) bool {
+    switch r := e.operand.eval(root, cur).(type) {
+    case jpNodes:
+        return len(r) > 0
+    case jpLogical:
+        return bool(r)
+    default:
+        return false
+    }
+}
+
+type jpComparison struct {
+    op          string
+    left, right jpOperand
+}
+
+func (e jpComparison) test(root, cur interface{}) bool {
+    a := jpToValue(e.left.eval(root, cur))
+    b := jpToValue(e.right.eval(root, cur))
+    switch e.op {
+    case "==":
+        return jpEqual(a, b)
+    case "!=":
+        return !jpEqual(a, b)
+    case "<":
+        return jpLess(a, b)
+    case "<=":
+        return jpLess(a, b) || jpEqual(a, b)
+    case ">":
+        return jpLess(b, a)
+    case ">=":
+        return jpLess(b, a) || jpEqual(a, b)
+    }
+    return false
+}
+
+// Results of operand evaluation other than plain JSON values.
+type jpNothing struct{}
+type jpNodes []jpNode
+type jpLogical bool
+
+func jpToValue(r interface{}) interface{} {
+    if nodes, ok := r.(jpNodes); ok {
+        if len(nodes) == 1 {
+            return nodes[0].value
+        }
+        return jpNothing{}
+    }
+    return r
+}
+
+func jpEqual(a, b interface{}) bool {
+    _, aNothing := a.(jpNothing)
+    _, bNothing := b.(jpNothing)
+    if aNothing || bNothing {
+        return aNothing && bNothing
+    }
+    return equal(a, b)
+}
+
+func jpLess(a, b interface{}) bool {
+    if x, ok := a.(string); ok {
+        y, ok := b.(string)
+        return ok && x < y
+    }
+    if isNumber(a) && isNumber(b) {
+        res, ok := compareNumbers(a, b)
+        return ok && res < 0
+    }
+    return false
+}
+
+const (
+    jpTypeValue = iota
+    jpTypeLogical
+    jpTypeNodes
+)
+
+type jpOperand interface {
+    eval(root, cur interface{}) interface{}
+}
+
+type jpLiteral struct {
+    value interface{}
+}
+
+func (o jpLiteral) eval(root, cur interface{}) interface{} {
+    return o.value
+}
+
+type jpQuery struct {
+    absolute bool
+    segments []jpSegment
+}
+
+func (o jpQuery) eval(root, cur interface{}) interface{} {
+    start := cur
+    if o.absolute {
+        start = root
+    }
+    return jpNodes(jpEvalSegments(o.segments, root, jpNode{value: start}))
+}
+
+func (o jpQuery) singular() bool {
+    for _, seg := range o.segments {
+        if seg.descendant || len(seg.selectors) != 1 {
+            return false
+        }
+        switch seg.selectors[0].(type) {
+        case jpNameSelector, jpIndexSelector:
+        default:
+            return false
+        }
+    }
+    return true
+}
+
+type jpFunction struct {
+    name string
+    args []jpOperand
+    re   *regexp.Regexp
+}
+
+type jpFunctionSignature struct {
+    params []int
+    result int
+}
+
+var jpFunctions = map[string]jpFunctionSignature{
+    "length": {[]int{jpTypeValue}, jpTypeValue},
+    "count":  {[]int{jpTypeNodes}, jpTypeValue},
+    "match":  {[]int{jpTypeValue, jpTypeValue}, jpTypeLogical},
+    "search": {[]int{jpTypeValue, jpTypeValue}, jpTypeLogical},
+    "value":  {[]int{jpTypeNodes}, jpTypeValue},
+}
+
+func (o jpFunction) eval(root, cur interface{}) interface{} {
+    switch o.name {
+    case "length":
+        switch v := jpToValue(o.args[0].eval(root, cur)).(type) {
+        case string:
+            return float64(utf8.RuneCountInString(v))
+        case []interface{}:
+            return float64(len(v))
+        case map[string]interface{}:
+            return float64(len(v))
+        case *Object:
+            if v != nil {
+                return float64(v.Len())
+            }
+        default:
+            if a, ok := jpArray(v); ok {
+                return float64(len(a))
+            }
+            if keys, _, ok := nativeMembers(v); ok {
+                return float64(len(keys))
+            }
+        }
+        return jpNothing{}
+    case "count":
+        nodes, _ := o.args[0].eval(root, cur).(jpNodes)
+        return float64(len(nodes))
+    case "match", "search":
+        s, ok := jpToValue(o.args[0].eval(root, cur)).(string)
+        if !ok {
+            return jpLogical(false)
+        }
+        re := o.re
+        if re == nil {
+            pattern, ok := jpToValue(o.args[1].eval(root, cur)).(string)
+            if !ok {
+                return jpLogical(false)
+            }
+            var err error
+            re, err = jpCompileRegexp(pattern, o.name == "match")
+            if err != nil {
+                return jpLogical(false)
+            }
+        }
+        return jpLogical(re.MatchString(s))
+    case "value":
+        return jpToValue(o.args[0].eval(root, cur))
+    }
+    return jpNothing{}
+}
+
+// Translates I-Regexp (RFC 9485) into Go regular expression.
+func jpCompileRegexp(pattern string, anchored bool) (*regexp.Regexp, error) {
+    var b strings.Builder
+    inClass, escaped := false, false
+    for _, c := range pattern {
+        switch {
+        case escaped:
+            escaped = false
+        case c == '\\':
+            escaped = true
+        case inClass:
+            if c == ']' {
+                inClass = false
+            }
+        case c == '[':
+            inClass = true
+        case c == '.':
+            b.WriteString(`[^\n\r]`)
+            continue
+        }
+        b.WriteRune(c)
+    }
+    if anchored {
+        return regexp.Compile(`^(?:` + b.String() + `)$`)
+    }
+    return regexp.Compile(b.String())
+}
+
+// Parser
+
+type jpParser struct {
+    pathParser
+}
+
+func (p *jpParser) skipWS() {
+    for p.pos < len(p.src) {
+        switch p.src[p.pos] {
+        case ' ', '\t', '\n', '\r':
+            p.pos++
+        default:
+            return
+        }
+    }
+}
+
+func (p *jpParser) peek() byte {
+    if p.pos < len(p.src) {
+        return p.src[p.pos]
+    }
+    return 0
+}
+
+func (p *jpParser) consume(s string) bool {
+    if strings.HasPrefix(p.src[p.pos:], s) {
+        p.pos += len(s)
+        return true
+    }
+    return false
+}
+
+func jpNameFirst(c byte) bool {
+    return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func jpNameChar(c byte) bool {
+    return jpNameFirst(c) || c >= '0' && c <= '9'
+}
+
+func (p *jpParser) segments() ([]jpSegment, error) {
+    var segments []jpSegment
+    for {
+        save := p.pos
+        p.skipWS()
+        var seg jpSegment
+        var err error
+        switch {
+        case p.consume(".."):
+            seg, err = p.segmentBody(true)
+        case p.consume("."):
+            seg, err = p.segmentBody(false)
+        case p.peek() == '[':
+            seg, err = p.bracketed(false)
+        default:
+            p.pos = save
+            return segments, nil
+        }
+        if err != nil {
+            return nil, err
+        }
+        segments = append(segments, seg)
+    }
+}
+
+// Parses segment after leading dot(s).
+func (p *jpParser) segmentBody(descendant bool) (jpSegment, error) {
+    switch c := p.peek(); {
+    case c == '*':
+        p.pos++
+        return jpSegment{descendant, []jpSelector{jpWildcardSelector{}}}, nil
+    case c == '[' && descendant:
+        return p.bracketed(true)
+    case jpNameFirst(c):
+        start := p.pos
+        for p.pos < len(p.src) && jpNameChar(p.src[p.pos]) {
+            p.pos++
+        }
+        return jpSegment{descendant, []jpSelector{jpNameSelector(p.src[start:p.pos])}}, nil
+    default:
+        return jpSegment{}, p.errorf("expected member name or '*'")
+    }
+}
+
+func (p *jpParser) bracketed(descendant bool) (jpSegment, error) {
+    p.pos++
+    seg := jpSegment{descendant: descendant}
+    for {
+        p.skipWS()
+        sel, err := p.selector()
+        if err != nil {
+            return seg, err
+        }
+        seg.selectors = append(seg.selectors, sel)
+        p.skipWS()
+        if p.consume("]") {
+            return seg, nil
+        }
+        if !p.consume(",") {
+            return seg, p.errorf("expected ',' or ']'")
+        }
+    }
+}
+
+func (p *jpParser) selector() (jpSelector, error) {
+    switch c := p.peek(); {
+    case c == '\'' || c == '"':
+        p.pos++
+        s, err := p.quoted(c)
+        if err != nil {
+            return nil, err
+        }
+        return jpNameSelector(s), nil
+    case c == '*':
+        p.pos++
+        return jpWildcardSelector{}, nil
+    case c == '?':
+        p.pos++
+        p.skipWS()
+        expr, err := p.logicalOr()
+        if err != nil {
+            return nil, err
+        }
+        return jpFilterSelector{expr}, nil
+    case c == '-' || c >= '0' && c <= '9' || c == ':':
+        return p.indexOrSlice()
+    default:
+        return nil, p.errorf("unexpected character %q", c)
+    }
+}
+
+func (p *jpParser) integer() (int, error) {
+    start := p.pos
+    p.consume("-")
+    digits := p.pos
+    for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+        p.pos++
+    }
+    num := p.src[start:p.pos]
+    if p.pos == digits || (p.src[digits] == '0' && (p.pos-digits > 1 || digits > start)) {
+        p.pos = start
+        return 0, p.errorf("bad integer %q", num)
+    }
+    res, err := strconv.ParseInt(num, 10, 54)
+    if err != nil {
+        p.pos = start
+        return 0, p.errorf("bad integer %q", num)
+    }
+    return int(res), nil
+}
+
+func (p *jpParser) optionalInteger() (*int, error) {
+    if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+        i, err := p.integer()
+        if err != nil {
+            return nil, err
+        }
+        return &i, nil
+    }
+    return nil, nil
+}
+
+func (p *jpParser) indexOrSlice() (jpSelector, error) {
+    start, err := p.optionalInteger()
+    if err != nil {
+        return nil, err
+    }
+    p.skipWS()
+    if !p.consume(":") {
+        if start == nil {
+            return nil, p.errorf("expected index")
+        }
+        return jpIndexSelector(*start), nil
+    }
+    sel := jpSliceSelector{start: start}
+    p.skipWS()
+    if sel.end, err = p.optionalInteger(); err != nil {
+        return nil, err
+    }
+    p.skipWS()
+    if p.consume(":") {
+        p.skipWS()
+        if sel.step, err = p.optionalInteger(); err != nil {
+            return nil, err
+        }
+    }
+    return sel, nil
+}
+
+func (p *jpParser) logicalOr() (jpLogicalExpr, error) {
+    var res jpOr
+    for {
+        expr, err := p.logicalAnd()
+        if err != nil {
+            return nil, err
+        }
+        res = append(res, expr)
+        p.skipWS()
+        if !p.consume("||") {
+            break
+        }
+        p.skipWS()
+    }
+    if len(res) == 1 {
+        return res[0], nil
+    }
+    return res, nil
+}
+
+func (p *jpParser) logicalAnd() (jpLogicalExpr, error) {
+    var res jpAnd
+    for {
+        expr, err := p.basicExpr()
+        if err != nil {
+            return nil, err
+        }
+        res = append(res, expr)
+        p.skipWS()
+        if !p.consume("&&") {
+            break
+        }
+        p.skipWS()
+    }
+    if len(res) == 1 {
+        return res[0], nil
+    }
+    return res, nil
+}
+
+func (p *jpParser) basicExpr() (jpLogicalExpr, error) {
+    if p.consume("!") {
+        p.skipWS()
+        if p.peek() == '(' {
+            expr, err := p.parenExpr()
+            if err != nil {
+                return nil, err
+            }
+            return jpNot{expr}, nil
+        }
+        expr, err := p.testExpr()
+        if err != nil {
+            return nil, err
+        }
+        return jpNot{expr}, nil
+    }
+    if p.peek() == '(' {
+        return p.parenExpr()
+    }
+    start := p.pos
+    left, ltype, err := p.operand()
+    if err != nil {
+        return nil, err
+    }
+    p.skipWS()
+    op := ""
+    for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+        if p.consume(candidate) {
+            op = candidate
+            break
+        }
+    }
+    if op == "" {
+        p.pos = start
+        return p.testExpr()
+    }
+    if err := p.checkComparable(left, ltype); err != nil {
+        return nil, err
+    }
+    p.skipWS()
+    right, rtype, err := p.operand()
+    if err != nil {
+        return nil, err
+    }
+    if err := p.checkComparable(right, rtype); err != nil {
+        return nil, err
+    }
+    return jpComparison{op, left, right}, nil
+}
+
+func (p *jpParser) parenExpr() (jpLogicalExpr, error) {
+    p.pos++
+    p.skipWS()
+    expr, err := p.logicalOr()
+    if err != nil {
+        return nil, err
+    }
+    p.skipWS()
+    if !p.consume(")") {
+        return nil, p.errorf("expected ')'")
+    }
+    return expr, nil
+}
+
+func (p *jpParser) testExpr() (jpLogicalExpr, error) {
+    operand, typ, err := p.operand()
+    if err != nil {
+        return nil, err
+    }
+    if _, ok := operand.(jpLiteral); ok || typ == jpTypeValue {
+        return nil, p.errorf("expression is not a test")
+    }
+    return jpTest{operand}, nil
+}
+
+func (p *jpParser) checkComparable(o jpOperand, typ int) error {
+    if q, ok := o.(jpQuery); ok {
+        if !q.singular() {
+            return p.errorf("non-singular query in comparison")
+        }
+        return nil
+    }
+    if typ != jpTypeValue {
+        return p.errorf("function result is not comparable")
+    }
+    return nil
+}
+
+// Parses literal, filter query or function call and returns its type.
+func (p *jpParser) operand() (jpOperand, int, error) {
+    switch c := p.peek(); {
+    case c == '@' || c == '$':
+        p.pos++
+        segments, err := p.segments()
+        if err != nil {
+            return nil, 0, err
+        }
+        return jpQuery{absolute: c == '$', segments: segments}, jpTypeNodes, nil
+    case c == '\'' || c == '"':
+        p.pos++
+        s, err := p.quoted(c)
+        if err != nil {
+            return nil, 0, err
+        }
+        return jpLiteral{s}, jpTypeValue, nil
+    case c == '-' || c >= '0' && c <= '9':
+        start := p.pos
+        for p.pos < len(p.src) && strings.IndexByte("+-.0123456789eE", p.src[p.pos]) >= 0 {
+            p.pos++
+        }
+        num := p.src[start:p.pos]
+        f, err := strconv.ParseFloat(num, 64)
+        if err != nil || !json.Valid([]byte(num)) {
+            p.pos = start
+            return nil, 0, p.errorf("bad number %q", num)
+        }
+        return jpLiteral{f}, jpTypeValue, nil
+    case c >= 'a' && c <= 'z':
+        start := p.pos
+        for p.pos < len(p.src) && (p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' ||
+            p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '_') {
+            p.pos++
+        }
+        name := p.src[start:p.pos]
+        if p.peek() != '(' {
+            switch name {
+            case "true":
+                return jpLiteral{true}, jpTypeValue, nil
+            case "false":
+                return jpLiteral{false}, jpTypeValue, nil
+            case "null":
+                return jpLiteral{nil}, jpTypeValue, nil
+            }
+            p.pos = start
+            return nil, 0, p.errorf("unexpected name %q", name)
+        }
+        return p.function(name, start)
+    default:
+        return nil, 0, p.errorf("unexpected character %q", c)
+    }
+}
+
+func (p *jpParser) function(name string, start int) (jpOperand, int, error) {
+    sig, ok := jpFunctions[name]
+    if !ok {
+        p.pos = start
+        return nil, 0, p.errorf("unknown function %q", name)
+    }
+    p.pos++
+    fn := jpFunction{name: name}
+    for i := 0; ; i++ {
+        p.skipWS()
+        if i == 0 && p.consume(")") {
+            break
+        }
+        if i > 0 {
+            if p.consume(")") {
+                break
+            }
+            if !p.consume(",") {
+                return nil, 0, p.errorf("expected ',' or ')'")
+            }
+            p.skipWS()
+        }
+        arg, typ, err := p.operand()
+        if err != nil {
+            return nil, 0, err
+        }
+        if i >= len(sig.params) {
+            return nil, 0, p.errorf("too many arguments for %s()", name)
+        }
+        switch sig.params[i] {
+        case jpTypeValue:
+            q, isQuery := arg.(jpQuery)
+            if isQuery && !q.singular() || !isQuery && typ != jpTypeValue {
+                return nil, 0, p.errorf("argument %d of %s() must be a value", i+1, name)
+            }
+        case jpTypeNodes:
+            if _, isQuery := arg.(jpQuery); !isQuery {
+                return nil, 0, p.errorf("argument %d of %s() must be a query", i+1, name)
+            }
+        }
+        fn.args = append(fn.args, arg)
+    }
+    if len(fn.args) != len(sig.params) {
+        return nil, 0, p.errorf("wrong number of arguments for %s()", name)
+    }
+    if name == "match" || name == "search" {
+        if lit, ok := fn.args[1].(jpLiteral); ok {
+            if pattern, ok := lit.value.(string); ok {
+                re, err := jpCompileRegexp(pattern, name == "match")
+                if err != nil {
+                    return nil, 0, p.errorf("bad regular expression %q", pattern)
+                }
+                fn.re = re
+            }
+        }
+    }
+    return fn, sig.result, nil
+}
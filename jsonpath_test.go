@@ -0,0 +1,164 @@
+package qjson
+
+import (
+    "reflect"
+    "testing"
+)
+
+var BOOKSTORE = `
+{
+    "store": {
+        "book": [
+            {
+                "category": "reference",
+                "author": "Nigel Rees",
+                "title": "Sayings of the Century",
+                "price": 8.95
+            },
+            {
+                "category": "fiction",
+                "author": "Evelyn Waugh",
+                "title": "Sword of Honour",
+                "price": 12.99
+            },
+            {
+                "category": "fiction",
+                "author": "Herman Melville",
+                "title": "Moby Dick",
+                "isbn": "0-553-21311-3",
+                "price": 8.99
+            },
+            {
+                "category": "fiction",
+                "author": "J. R. R. Tolkien",
+                "title": "The Lord of the Rings",
+                "isbn": "0-395-19395-8",
+                "price": 22.99
+            }
+        ],
+        "bicycle": {
+            "color": "red",
+            "price": 399
+        }
+    }
+}
+`
+
+func jsonPathValues(t *testing.T, j interface{}, expr string) []interface{} {
+    matches, err := QJSONPath(j, expr)
+    if err != nil {
+        t.Fatalf("QJSONPath(%q) failed: %v", expr, err)
+    }
+    res := []interface{}{}
+    for _, m := range matches {
+        res = append(res, m.Value)
+    }
+    return res
+}
+
+func TestJSONPathQueries(t *testing.T) {
+    j := loadJSON(BOOKSTORE, t)
+    cases := []struct {
+        expr   string
+        values []interface{}
+    }{
+        {`$.store.book[*].author`, []interface{}{"Nigel Rees", "Evelyn Waugh", "Herman Melville", "J. R. R. Tolkien"}},
+        {`$..author`, []interface{}{"Nigel Rees", "Evelyn Waugh", "Herman Melville", "J. R. R. Tolkien"}},
+        {`$.store..price`, []interface{}{399., 8.95, 12.99, 8.99, 22.99}},
+        {`$..book[2].title`, []interface{}{"Moby Dick"}},
+        {`$..book[-1].title`, []interface{}{"The Lord of the Rings"}},
+        {`$..book[0,1].title`, []interface{}{"Sayings of the Century", "Sword of Honour"}},
+        {`$..book[:2].title`, []interface{}{"Sayings of the Century", "Sword of Honour"}},
+        {`$..book[::-2].title`, []interface{}{"The Lord of the Rings", "Sword of Honour"}},
+        {`$..book[?@.isbn].title`, []interface{}{"Moby Dick", "The Lord of the Rings"}},
+        {`$..book[?@.price<10].title`, []interface{}{"Sayings of the Century", "Moby Dick"}},
+        {`$..book[?@.price > 10 && @.category == 'fiction'].title`, []interface{}{"Sword of Honour", "The Lord of the Rings"}},
+        {`$..book[?!(@.price > 10) || @.author == $.store.book[3].author].price`, []interface{}{8.95, 8.99, 22.99}},
+        {`$.store.book[?match(@.author, 'H.*')].title`, []interface{}{"Moby Dick"}},
+        {`$.store.book[?search(@.title, 'of')].title`, []interface{}{"Sayings of the Century", "Sword of Honour", "The Lord of the Rings"}},
+        {`$.store.book[?length(@.title) == 9].title`, []interface{}{"Moby Dick"}},
+        {`$.store[?count(@.*) > 2].color`, []interface{}{}},
+        {`$.store[?count(@.*) == 2].color`, []interface{}{"red"}},
+        {`$.store.book[?value(@..isbn) == "0-553-21311-3"].title`, []interface{}{"Moby Dick"}},
+        {`$["store"]['bicycle'].color`, []interface{}{"red"}},
+        {`$.nonexistent`, []interface{}{}},
+        {`$`, []interface{}{j}},
+    }
+    for _, c := range cases {
+        values := jsonPathValues(t, j, c.expr)
+        if !reflect.DeepEqual(values, c.values) {
+            t.Errorf("QJSONPath(%q) = %#v", c.expr, values)
+        }
+    }
+}
+
+func TestJSONPathMatchPaths(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    matches, err := QJSONPath(j, `$.menu.popup.menuitem[*].value`)
+    if err != nil || len(matches) != 3 {
+        t.FailNow()
+    }
+    m := matches[1]
+    if !reflect.DeepEqual(m.Path, []interface{}{"menu", "popup", "menuitem", 1, "value"}) {
+        t.Fail()
+    }
+    if m.NormalizedPath() != `$['menu']['popup']['menuitem'][1]['value']` {
+        t.Fail()
+    }
+    if m.Pointer() != `/menu/popup/menuitem/1/value` {
+        t.Fail()
+    }
+    v, err := Q(j, m.Path...)
+    if err != nil || v != m.Value {
+        t.Fail()
+    }
+    if (Match{Path: []interface{}{"it's\n"}}).NormalizedPath() != `$['it\'s\n']` {
+        t.Fail()
+    }
+}
+
+func TestJSONPathSlices(t *testing.T) {
+    j := loadJSON(`[0, 1, 2, 3, 4, 5, 6]`, t)
+    cases := map[string][]interface{}{
+        `$[1:3]`:    {1., 2.},
+        `$[5:]`:     {5., 6.},
+        `$[1:5:2]`:  {1., 3.},
+        `$[5:1:-2]`: {5., 3.},
+        `$[::-1]`:   {6., 5., 4., 3., 2., 1., 0.},
+        `$[-2:]`:    {5., 6.},
+        `$[::0]`:    {},
+        `$[10:20]`:  {},
+    }
+    for expr, ref := range cases {
+        values := jsonPathValues(t, j, expr)
+        if !reflect.DeepEqual(values, ref) {
+            t.Errorf("QJSONPath(%q) = %#v", expr, values)
+        }
+    }
+}
+
+func TestJSONPathSyntaxErrors(t *testing.T) {
+    for _, expr := range []string{
+        ``, `store`, `$.`, `$[`, `$[01]`, `$[-0]`, `$['a'`, `$.a b`,
+        `$[?@.a == @..b]`, `$[?@.a == 'x' ||]`, `$[?foo(@)]`, `$[?length(@.*)]`,
+        `$[?count(1) == 1]`, `$[?1]`, `$[?match(@.a, '(')]`,
+    } {
+        _, err := CompileJSONPath(expr)
+        if _, ok := err.(ArgError) ; !ok {
+            t.Errorf("CompileJSONPath(%q) error = %v", expr, err)
+        }
+    }
+}
+
+func TestMustCompileJSONPath(t *testing.T) {
+    p := MustCompileJSONPath(`$.a`)
+    if p.String() != `$.a` {
+        t.Fail()
+    }
+    defer func() {
+        if recover() == nil {
+            t.Fail()
+        }
+    }()
+    MustCompileJSONPath(`a`)
+}
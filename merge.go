@@ -0,0 +1,84 @@
+package qjson
+
+// Apply RFC 7386 JSON Merge Patch to target and return result: objects are
+// merged recursively, null values delete keys and all other values replace
+// target values. Neither target nor patch are modified.
+func MergePatch(target, patch interface{}) interface{} {
+    return mergePatch(deepCopy(target), patch)
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+    p, ok := asMap(patch)
+    if !ok {
+        return deepCopy(patch)
+    }
+    keys := sortedKeys(p)
+    if o, ok := patch.(*Object); ok {
+        keys = o.Keys()
+    }
+    if t, ok := asObject(target); ok {
+        // Keep order of ordered target, new keys follow order of patch
+        for _, k := range keys {
+            if v := p[k]; isNull(v) {
+                t.Delete(k)
+            } else {
+                old, _ := t.Get(k)
+                t.Set(k, mergePatch(old, v))
+            }
+        }
+        return t
+    }
+    t, ok := target.(map[string]interface{})
+    if !ok {
+        if _, ordered := patch.(*Object); ordered {
+            return mergePatch(NewObject(), patch)
+        }
+        t = make(map[string]interface{}, len(p))
+    }
+    for k, v := range p {
+        if isNull(v) {
+            delete(t, k)
+        } else {
+            t[k] = mergePatch(t[k], v)
+        }
+    }
+    return t
+}
+
+// Create RFC 7386 JSON Merge Patch which transforms original into modified.
+// Merge Patch can't express null values in objects, so ArgError is returned
+// if modified document contains null object member which is absent or not
+// null in original document.
+func CreateMergePatch(original, modified interface{}) (interface{}, error) {
+    patch := createMergePatch(original, modified)
+    if !equal(MergePatch(original, patch), modified) {
+        return nil, newArgError("Merge patch can't represent null values in objects")
+    }
+    return patch, nil
+}
+
+func createMergePatch(original, modified interface{}) interface{} {
+    o, ok := asMap(original)
+    if !ok {
+        return deepCopy(modified)
+    }
+    m, ok := asMap(modified)
+    if !ok {
+        return deepCopy(modified)
+    }
+    res := make(map[string]interface{})
+    for k := range o {
+        if _, ok := m[k]; !ok {
+            res[k] = nil
+        }
+    }
+    for k, mv := range m {
+        ov, ok := o[k]
+        if !ok {
+            res[k] = deepCopy(mv)
+        } else if !equal(ov, mv) {
+            res[k] = createMergePatch(ov, mv)
+        }
+    }
+    return res
+}
@@ -0,0 +1,73 @@
+package qjson
+
+import (
+    "testing"
+)
+
+func TestMergePatch(t *testing.T) {
+    cases := []struct {
+        target, patch, result string
+    }{
+        {`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+        {`{"a":"b"}`, `{"a":null}`, `{}`},
+        {`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+        {`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+        {`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+        {`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+        {`["a","b"]`, `["c","d"]`, `["c","d"]`},
+        {`{"a":"b"}`, `["c"]`, `["c"]`},
+        {`{"a":"foo"}`, `null`, `null`},
+        {`{"a":"foo"}`, `"bar"`, `"bar"`},
+        {`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+        {`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+        {`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+    }
+    for _, c := range cases {
+        target := loadJSON(c.target, t)
+        ref := dumpJSON(target, t)
+        res := MergePatch(target, loadJSON(c.patch, t))
+        if dumpJSON(res, t) != c.result {
+            t.Errorf("MergePatch(%s, %s) = %s", c.target, c.patch, dumpJSON(res, t))
+        }
+        if dumpJSON(target, t) != ref {
+            t.Errorf("MergePatch(%s, %s) modified target", c.target, c.patch)
+        }
+    }
+}
+
+func TestCreateMergePatch(t *testing.T) {
+    cases := []struct {
+        original, modified, patch string
+    }{
+        {`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":"b","b":"c"}`, `{"b":"c"}`, `{"a":null}`},
+        {`{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"d","d":"e"}}`, `{"a":{"b":"d"}}`},
+        {`{"a":[1,2]}`, `{"a":[1,2,3]}`, `{"a":[1,2,3]}`},
+        {`{"a":1}`, `{"a":1}`, `{}`},
+        {`[1]`, `{"a":1}`, `{"a":1}`},
+        {`{"a":1}`, `[null]`, `[null]`},
+        {`{"a":null}`, `{"a":null,"b":1}`, `{"b":1}`},
+    }
+    for _, c := range cases {
+        original := loadJSON(c.original, t)
+        modified := loadJSON(c.modified, t)
+        patch, err := CreateMergePatch(original, modified)
+        if err != nil || dumpJSON(patch, t) != c.patch {
+            t.Errorf("CreateMergePatch(%s, %s) = %s, %v", c.original, c.modified, dumpJSON(patch, t), err)
+        }
+        if dumpJSON(MergePatch(original, patch), t) != dumpJSON(modified, t) {
+            t.Errorf("Patch created from %s to %s is not reversible", c.original, c.modified)
+        }
+    }
+    for _, c := range [][2]string{
+        {`{"a":1}`, `{"a":null}`},
+        {`{}`, `{"a":{"b":null}}`},
+    } {
+        _, err := CreateMergePatch(loadJSON(c[0], t), loadJSON(c[1], t))
+        if _, ok := err.(ArgError) ; !ok {
+            t.Errorf("CreateMergePatch(%s, %s) error = %v", c[0], c[1], err)
+        }
+    }
+}
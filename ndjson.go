@@ -0,0 +1,217 @@
+package qjson
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+)
+
+// This error is returned when NDJSON record fails to parse or to be
+// processed. It wraps ParseError or error returned by record operation.
+type LineError struct {
+    line int
+    err  error
+}
+
+func (e LineError) Error() string {
+    return fmt.Sprintf("Line %d: %v", e.line, e.err)
+}
+
+// Returns number of input line holding failed record, starting from 1.
+func (e LineError) Line() int {
+    return e.line
+}
+
+// Returns underlying error.
+func (e LineError) Unwrap() error {
+    return e.err
+}
+
+// Reads newline-delimited JSON records one by one. Empty lines are skipped.
+type NDJSONReader struct {
+    r    *bufio.Reader
+    opts []ParseOption
+    line int
+    err  error
+}
+
+// Creates NDJSON reader. Each record is parsed with Parse() using opts.
+func NewNDJSONReader(r io.Reader, opts ...ParseOption) *NDJSONReader {
+    return &NDJSONReader{
+        r:    bufio.NewReader(r),
+        opts: append(append([]ParseOption{}, opts...), DisallowTrailingData()),
+    }
+}
+
+// Returns next record. Malformed record is reported with LineError wrapping
+// ParseError; reading may be continued after it with the following line.
+// io.EOF is returned once input is exhausted.
+func (n *NDJSONReader) Next() (interface{}, error) {
+    for n.err == nil {
+        data, err := n.r.ReadBytes('\n')
+        if err != nil {
+            n.err = err
+            if err != io.EOF {
+                return nil, err
+            }
+        }
+        if len(data) == 0 {
+            break
+        }
+        n.line++
+        data = bytes.TrimSpace(data)
+        if len(data) == 0 {
+            continue
+        }
+        V, err := Parse(data, n.opts...)
+        if err != nil {
+            return nil, LineError{line: n.line, err: err}
+        }
+        return V, nil
+    }
+    return nil, n.err
+}
+
+// Returns number of the last read line.
+func (n *NDJSONReader) Line() int {
+    return n.line
+}
+
+// Writes values as newline-delimited JSON records.
+type NDJSONWriter struct {
+    enc *json.Encoder
+}
+
+// Creates NDJSON writer.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+    enc := json.NewEncoder(w)
+    enc.SetEscapeHTML(false)
+    return &NDJSONWriter{enc: enc}
+}
+
+// Encodes V into a single line.
+func (n *NDJSONWriter) Write(V interface{}) error {
+    return n.enc.Encode(V)
+}
+
+// Operation applied to every record by ProcessNDJSON(). It may modify
+// record in place or replace it.
+type RecordFunc func(record *interface{}) error
+
+// Sentinel error which makes ProcessNDJSON() drop record without reporting
+// it to error policy. Record operation may return it wrapped.
+var ErrSkipRecord = errors.New("skip record")
+
+// Returns record operation which replaces record with value found by Q().
+func StepQ(keys ...interface{}) RecordFunc {
+    return func(record *interface{}) error {
+        res, err := Q(*record, keys...)
+        if err != nil {
+            return err
+        }
+        *record = res
+        return nil
+    }
+}
+
+// Returns record operation which updates record with U().
+func StepU(keys ...interface{}) RecordFunc {
+    return func(record *interface{}) error {
+        _, err := U(record, keys...)
+        return err
+    }
+}
+
+// Returns record operation which deletes value from record with D().
+func StepD(keys ...interface{}) RecordFunc {
+    return func(record *interface{}) error {
+        _, err := D(record, keys...)
+        return err
+    }
+}
+
+// Decides how ProcessNDJSON() handles failed record. Returning nil drops
+// the record and continues processing, returned error stops it.
+type ErrorPolicy func(err LineError) error
+
+// Error policy which stops processing on the first failed record.
+func StopOnError(err LineError) error {
+    return err
+}
+
+// Error policy which drops malformed lines and stops processing on records
+// failed by record operations.
+func SkipMalformed(err LineError) error {
+    var perr ParseError
+    if errors.As(err, &perr) {
+        return nil
+    }
+    return err
+}
+
+// Error policy which drops every failed record.
+func SkipFailed(err LineError) error {
+    return nil
+}
+
+// Reads NDJSON records from r, applies steps to every record in order and
+// writes results to w. Records are parsed with UseNumber() and
+// OrderedObjects() options, so numbers and key order of unchanged data are
+// preserved. Failed records are passed to policy, nil policy is the same as
+// StopOnError. Errors of reading and writing always stop processing.
+// Returns number of written records.
+func ProcessNDJSON(r io.Reader, w io.Writer, policy ErrorPolicy, steps ...RecordFunc) (int, error) {
+    reader := NewNDJSONReader(r, UseNumber(), OrderedObjects())
+    return ProcessNDJSONReader(reader, w, policy, steps...)
+}
+
+// Same as ProcessNDJSON(), but takes records from r, which allows to choose
+// parse options.
+func ProcessNDJSONReader(r *NDJSONReader, w io.Writer, policy ErrorPolicy, steps ...RecordFunc) (int, error) {
+    if policy == nil {
+        policy = StopOnError
+    }
+    writer := NewNDJSONWriter(w)
+    written := 0
+    for {
+        record, err := r.Next()
+        if err == io.EOF {
+            return written, nil
+        }
+        if err == nil {
+            err = applySteps(&record, steps)
+            if errors.Is(err, ErrSkipRecord) {
+                continue
+            }
+            if err != nil {
+                err = LineError{line: r.Line(), err: err}
+            }
+        }
+        if err != nil {
+            lerr, ok := err.(LineError)
+            if !ok {
+                return written, err
+            }
+            if err := policy(lerr); err != nil {
+                return written, err
+            }
+            continue
+        }
+        if err := writer.Write(record); err != nil {
+            return written, err
+        }
+        written++
+    }
+}
+
+func applySteps(record *interface{}, steps []RecordFunc) error {
+    for _, step := range steps {
+        if err := step(record); err != nil {
+            return err
+        }
+    }
+    return nil
+}
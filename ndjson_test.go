@@ -0,0 +1,137 @@
+package qjson
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+    "testing"
+)
+
+const NDJSON_LOG = `{"level": "info", "msg": "started", "ctx": {"port": 8080}}
+
+{"level": "error", "msg": "<failed>", "ctx": {"port": 8081}}
+{"level": "info", "msg": broken}
+{"level": "debug", "msg": "no context"}
+`
+
+func TestNDJSONReader(t *testing.T) {
+    r := NewNDJSONReader(strings.NewReader(NDJSON_LOG))
+    var msgs []string
+    var lerr LineError
+    for {
+        V, err := r.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            if !errors.As(err, &lerr) || lerr.Line() != 4 || !errors.Is(err, ErrSyntax) {
+                t.Errorf("unexpected error: %v", err)
+            }
+            continue
+        }
+        msg, err := QString(V, "msg")
+        if err != nil {
+            t.Fatal(err)
+        }
+        msgs = append(msgs, msg)
+    }
+    if strings.Join(msgs, ",") != "started,<failed>,no context" || r.Line() != 5 {
+        t.Fail()
+    }
+    // Last line may lack newline
+    r = NewNDJSONReader(strings.NewReader("1\n2"), UseNumber())
+    for _, expected := range []string{"1", "2"} {
+        if V, err := r.Next(); err != nil || dumpJSON(V, t) != expected {
+            t.Fail()
+        }
+    }
+    if _, err := r.Next(); err != io.EOF {
+        t.Fail()
+    }
+}
+
+func TestNDJSONWriter(t *testing.T) {
+    var buf bytes.Buffer
+    w := NewNDJSONWriter(&buf)
+    for _, V := range []interface{}{loadJSON(`{"a": "<b>"}`, t), loadOrdered(`{"z": 1, "a": [2]}`, t), nil} {
+        if err := w.Write(V); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if buf.String() != "{\"a\":\"<b>\"}\n{\"z\":1,\"a\":[2]}\nnull\n" {
+        t.Errorf("unexpected output: %q", buf.String())
+    }
+}
+
+func TestProcessNDJSON(t *testing.T) {
+    var buf bytes.Buffer
+    n, err := ProcessNDJSON(strings.NewReader(NDJSON_LOG), &buf, SkipMalformed,
+        StepD("level"), StepU("ctx", "seen", true))
+    if err != nil || n != 3 {
+        t.Fatal(n, err)
+    }
+    expected := `{"msg":"started","ctx":{"port":8080,"seen":true}}
+{"msg":"<failed>","ctx":{"port":8081,"seen":true}}
+{"msg":"no context","ctx":{"seen":true}}
+`
+    if buf.String() != expected {
+        t.Errorf("unexpected output: %s", buf.String())
+    }
+
+    // Query failures carry line number and location
+    buf.Reset()
+    n, err = ProcessNDJSON(strings.NewReader(NDJSON_LOG), &buf, SkipMalformed, StepQ("ctx", "port"))
+    var lerr LineError
+    var kerr KeyError
+    if n != 2 || !errors.As(err, &lerr) || lerr.Line() != 5 || !errors.As(err, &kerr) ||
+        kerr.Key() != "ctx" || buf.String() != "8080\n8081\n" {
+        t.Errorf("unexpected result: %d, %v", n, err)
+    }
+
+    // Default policy stops on malformed line
+    buf.Reset()
+    n, err = ProcessNDJSON(strings.NewReader(NDJSON_LOG), &buf, nil)
+    if n != 2 || !errors.As(err, &lerr) || lerr.Line() != 4 || !errors.Is(err, ErrSyntax) {
+        t.Errorf("unexpected result: %d, %v", n, err)
+    }
+
+    // Custom policy collects failures
+    var failed []int
+    collect := func(err LineError) error {
+        failed = append(failed, err.Line())
+        return nil
+    }
+    buf.Reset()
+    n, err = ProcessNDJSON(strings.NewReader(NDJSON_LOG), &buf, collect, StepQ("ctx", "port"))
+    if err != nil || n != 2 || len(failed) != 2 || failed[0] != 4 || failed[1] != 5 {
+        t.Errorf("unexpected result: %d, %v, %v", n, err, failed)
+    }
+
+    // Records may be filtered
+    onlyErrors := func(record *interface{}) error {
+        if level, _ := QString(*record, "level"); level != "error" {
+            return fmt.Errorf("level %s: %w", level, ErrSkipRecord)
+        }
+        return nil
+    }
+    buf.Reset()
+    n, err = ProcessNDJSON(strings.NewReader(NDJSON_LOG), &buf, SkipFailed, onlyErrors, StepQ("msg"))
+    if err != nil || n != 1 || buf.String() != "\"<failed>\"\n" {
+        t.Errorf("unexpected result: %d, %v", n, err)
+    }
+
+    // Numbers and key order are preserved by default
+    const ids = `{"z": 1, "id": 1234567890123456789, "f": 1.50}`
+    buf.Reset()
+    n, err = ProcessNDJSON(strings.NewReader(ids), &buf, nil)
+    if err != nil || n != 1 || buf.String() != `{"z":1,"id":1234567890123456789,"f":1.50}`+"\n" {
+        t.Errorf("unexpected result: %d, %v, %s", n, err, buf.String())
+    }
+    buf.Reset()
+    n, err = ProcessNDJSONReader(NewNDJSONReader(strings.NewReader(ids)), &buf, nil)
+    if err != nil || n != 1 || buf.String() != `{"f":1.5,"id":1234567890123456800,"z":1}`+"\n" {
+        t.Errorf("unexpected result: %d, %v, %s", n, err, buf.String())
+    }
+}
@@ -0,0 +1,340 @@
+package qjson
+
+import (
+    "bytes"
+    "encoding"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "math"
+    "math/big"
+    "reflect"
+    "strconv"
+)
+
+var (
+    jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+    textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Converts arbitrary Go value into tree of the same types json.Unmarshal()
+// produces: map[string]interface{}, []interface{}, string, bool, float64 and
+// nil. Numbers which can't be represented by float64 exactly are kept as
+// json.Number. Values are converted by the rules of json.Marshal(): struct
+// fields are named by `json` tags, json.Marshaler and encoding.TextMarshaler
+// are used when implemented and []byte becomes base64 string. Values which
+// have no JSON representation, like functions, channels, complex numbers,
+// NaN, infinities and cyclic structures, are rejected with TypeError located
+// at the offending value. Containers are always copied, so result shares no
+// mutable state with V.
+func Normalize(V interface{}) (interface{}, error) {
+    n := normalizer{seen: map[seenKey]bool{}}
+    return n.normalize(reflect.ValueOf(V), make([]interface{}, 0, 8))
+}
+
+// Same as Normalize(), but errors are located relative to document root,
+// with V placed at path.
+func normalizeAt(path []interface{}, V interface{}) (interface{}, error) {
+    res, err := Normalize(V)
+    if e, ok := err.(TypeError); ok {
+        e.path = append(append([]interface{}{}, path...), e.path...)
+        e.depth = len(e.path)
+        err = e
+    }
+    return res, err
+}
+
+// Same as U(), but normalizes new value with Normalize() before inserting
+// it, so tree remains traversable by Q() and friends.
+func UNormalize(V *interface{}, keys ...interface{}) (interface{}, error) {
+    l := len(keys)
+    if l < 1 {
+        return nil, newArgError("Incorrect arg length")
+    }
+    newval, err := normalizeAt(keys[:l-1], keys[l-1])
+    if err != nil {
+        return nil, err
+    }
+    args := append(append([]interface{}{}, keys[:l-1]...), newval)
+    return U(V, args...)
+}
+
+// Identifies reference visited on current traversal path. Slices are
+// distinguished by length as well, since they may share backing array.
+type seenKey struct {
+    ptr uintptr
+    typ reflect.Type
+    len int
+}
+
+type normalizer struct {
+    seen map[seenKey]bool
+}
+
+func (n *normalizer) fail(msg string, v reflect.Value, path []interface{}) error {
+    var V interface{}
+    if v.IsValid() && v.CanInterface() {
+        V = v.Interface()
+    }
+    return locate(newTypeError(msg), path, len(path), V)
+}
+
+// Canonicalizes number: float64 is used unless it loses precision.
+func canonicalNumber(num json.Number) interface{} {
+    f, err := strconv.ParseFloat(string(num), 64)
+    if err != nil {
+        return num
+    }
+    exact, ok := new(big.Rat).SetString(string(num))
+    if !ok {
+        return num
+    }
+    if approx, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64)); approx.Cmp(exact) != 0 {
+        return num
+    }
+    return f
+}
+
+func (n *normalizer) marshaled(data []byte, v reflect.Value, path []interface{}) (interface{}, error) {
+    var res interface{}
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+    if err := dec.Decode(&res); err != nil {
+        return nil, n.fail(fmt.Sprintf("Bad JSON produced by %v: %v", v.Type(), err), v, path)
+    }
+    // Values returned by decoder are JSON-compatible already, only numbers
+    // need canonicalization.
+    return n.normalize(reflect.ValueOf(res), path)
+}
+
+func (n *normalizer) enter(v reflect.Value, length int, path []interface{}) error {
+    key := seenKey{v.Pointer(), v.Type(), length}
+    if n.seen[key] {
+        return n.fail(fmt.Sprintf("Cycle detected via %v", v.Type()), v, path)
+    }
+    n.seen[key] = true
+    return nil
+}
+
+func (n *normalizer) leave(v reflect.Value, length int) {
+    delete(n.seen, seenKey{v.Pointer(), v.Type(), length})
+}
+
+func (n *normalizer) normalize(v reflect.Value, path []interface{}) (interface{}, error) {
+    if !v.IsValid() {
+        return nil, nil
+    }
+    if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+        return nil, nil
+    }
+
+    // Ordered objects are kept ordered
+    if o, ok := ordered(v); ok {
+        ov := reflect.ValueOf(o)
+        if err := n.enter(ov, 0, path); err != nil {
+            return nil, err
+        }
+        defer n.leave(ov, 0)
+        res := NewObject()
+        for _, k := range o.Keys() {
+            elem, err := n.normalize(reflect.ValueOf(o.values[k]), append(path, k))
+            if err != nil {
+                return nil, err
+            }
+            res.Set(k, elem)
+        }
+        return res, nil
+    }
+
+    // Marshalers take precedence, like in json.Marshal()
+    t := v.Type()
+    if m, ok := marshaler(v, jsonMarshalerType); ok {
+        data, err := m.(json.Marshaler).MarshalJSON()
+        if err != nil {
+            return nil, n.fail(err.Error(), v, path)
+        }
+        return n.marshaled(data, v, path)
+    }
+    if m, ok := marshaler(v, textMarshalerType); ok {
+        text, err := m.(encoding.TextMarshaler).MarshalText()
+        if err != nil {
+            return nil, n.fail(err.Error(), v, path)
+        }
+        return string(text), nil
+    }
+
+    switch v.Kind() {
+    case reflect.Bool:
+        return v.Bool(), nil
+    case reflect.String:
+        if t == jsonNumberType {
+            num, err := toJSONNumber(json.Number(v.String()))
+            if err != nil {
+                return nil, n.fail(fmt.Sprintf("Value %q is not a valid number", v.String()), v, path)
+            }
+            return canonicalNumber(num), nil
+        }
+        return v.String(), nil
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return canonicalNumber(json.Number(strconv.FormatInt(v.Int(), 10))), nil
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return canonicalNumber(json.Number(strconv.FormatUint(v.Uint(), 10))), nil
+    case reflect.Float32, reflect.Float64:
+        f := v.Float()
+        if math.IsNaN(f) || math.IsInf(f, 0) {
+            return nil, n.fail(fmt.Sprintf("Value %v is not representable in JSON", f), v, path)
+        }
+        return canonicalNumber(json.Number(strconv.FormatFloat(f, 'g', -1, t.Bits()))), nil
+    case reflect.Interface:
+        return n.normalize(v.Elem(), path)
+    case reflect.Ptr:
+        if err := n.enter(v, 0, path); err != nil {
+            return nil, err
+        }
+        defer n.leave(v, 0)
+        return n.normalize(v.Elem(), path)
+    case reflect.Slice:
+        if v.IsNil() {
+            return nil, nil
+        }
+        if t.Elem().Kind() == reflect.Uint8 {
+            if _, ok := marshaler(reflect.New(t.Elem()).Elem(), jsonMarshalerType); !ok {
+                return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+            }
+        }
+        if err := n.enter(v, v.Len(), path); err != nil {
+            return nil, err
+        }
+        defer n.leave(v, v.Len())
+        return n.normalizeArray(v, path)
+    case reflect.Array:
+        return n.normalizeArray(v, path)
+    case reflect.Map:
+        if v.IsNil() {
+            return nil, nil
+        }
+        if err := n.enter(v, 0, path); err != nil {
+            return nil, err
+        }
+        defer n.leave(v, 0)
+        return n.normalizeMap(v, path)
+    case reflect.Struct:
+        return n.normalizeStruct(v, path)
+    default:
+        return nil, n.fail(fmt.Sprintf("Value of type %v is not representable in JSON", t), v, path)
+    }
+}
+
+func ordered(v reflect.Value) (*Object, bool) {
+    if !v.CanInterface() {
+        return nil, false
+    }
+    return asObject(v.Interface())
+}
+
+// Returns marshaler of given interface type implemented by v or, when v is
+// addressable, by its address.
+func marshaler(v reflect.Value, iface reflect.Type) (interface{}, bool) {
+    if v.Type().Implements(iface) && v.CanInterface() {
+        return v.Interface(), true
+    }
+    if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(iface) &&
+        v.Addr().CanInterface() {
+        return v.Addr().Interface(), true
+    }
+    return nil, false
+}
+
+func (n *normalizer) normalizeArray(v reflect.Value, path []interface{}) (interface{}, error) {
+    res := make([]interface{}, v.Len())
+    for i := range res {
+        elem, err := n.normalize(v.Index(i), append(path, i))
+        if err != nil {
+            return nil, err
+        }
+        res[i] = elem
+    }
+    return res, nil
+}
+
+func (n *normalizer) normalizeMap(v reflect.Value, path []interface{}) (interface{}, error) {
+    res := make(map[string]interface{}, v.Len())
+    iter := v.MapRange()
+    for iter.Next() {
+        k := iter.Key()
+        var key string
+        switch {
+        case k.Kind() == reflect.String:
+            key = k.String()
+        case k.Type().Implements(textMarshalerType):
+            if k.Kind() == reflect.Ptr && k.IsNil() {
+                key = ""
+                break
+            }
+            text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+            if err != nil {
+                return nil, n.fail(err.Error(), v, path)
+            }
+            key = string(text)
+        default:
+            switch k.Kind() {
+            case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+                key = strconv.FormatInt(k.Int(), 10)
+            case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+                key = strconv.FormatUint(k.Uint(), 10)
+            default:
+                return nil, n.fail(fmt.Sprintf("Map key of type %v is not representable in JSON", k.Type()), v, path)
+            }
+        }
+        elem, err := n.normalize(iter.Value(), append(path, key))
+        if err != nil {
+            return nil, err
+        }
+        res[key] = elem
+    }
+    return res, nil
+}
+
+func (n *normalizer) normalizeStruct(v reflect.Value, path []interface{}) (interface{}, error) {
+    fields := structFields(v.Type())
+    res := make(map[string]interface{}, len(fields))
+    for i := range fields {
+        f := &fields[i]
+        fv := fieldByIndex(v, f.index, false)
+        if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
+            continue
+        }
+        elem, err := n.normalize(fv, append(path, f.name))
+        if err != nil {
+            return nil, err
+        }
+        if f.quoted {
+            switch elem.(type) {
+            case string, bool, float64, json.Number:
+                data, _ := json.Marshal(elem)
+                elem = string(data)
+            }
+        }
+        res[f.name] = elem
+    }
+    return res, nil
+}
+
+// Reports whether value is omitted by ",omitempty" option.
+func isEmptyValue(v reflect.Value) bool {
+    switch v.Kind() {
+    case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+        return v.Len() == 0
+    case reflect.Bool:
+        return !v.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return v.Int() == 0
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return v.Uint() == 0
+    case reflect.Float32, reflect.Float64:
+        return v.Float() == 0
+    case reflect.Interface, reflect.Ptr:
+        return v.IsNil()
+    }
+    return false
+}
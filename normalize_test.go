@@ -0,0 +1,187 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "net"
+    "testing"
+    "time"
+)
+
+type celsius float64
+
+type pointKey struct {
+    X, Y int
+}
+
+func (k pointKey) MarshalText() ([]byte, error) {
+    return []byte(fmt.Sprintf("%d:%d", k.X, k.Y)), nil
+}
+
+type normalizeInner struct {
+    Note string `json:"note,omitempty"`
+}
+
+type normalizeRecord struct {
+    *normalizeInner
+    Name    string              `json:"name"`
+    Count   int                 `json:"count,string"`
+    Temp    celsius             `json:"temp"`
+    Tags    []string            `json:"tags"`
+    Scores  map[int]uint8       `json:"scores"`
+    Blob    []byte              `json:"blob"`
+    When    time.Time           `json:"when"`
+    Addr    net.IP              `json:"addr"`
+    Points  map[pointKey]string `json:"points,omitempty"`
+    Raw     json.RawMessage     `json:"raw"`
+    Hidden  string              `json:"-"`
+    Empty   *normalizeInner     `json:"empty"`
+    Nested  []map[string][2]int `json:"nested"`
+    private int
+}
+
+type normalizeNode struct {
+    Next *normalizeNode `json:"next"`
+}
+
+func TestNormalizeStruct(t *testing.T) {
+    r := normalizeRecord{
+        normalizeInner: &normalizeInner{"inner"},
+        Name:           "x",
+        Count:          3,
+        Temp:           36.6,
+        Tags:           []string{"a", "b"},
+        Scores:         map[int]uint8{1: 10},
+        Blob:           []byte("hello"),
+        When:           time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+        Addr:           net.IPv4(127, 0, 0, 1),
+        Raw:            json.RawMessage(`{"big": 12345678901234567890, "small": 1.5}`),
+        Hidden:         "hidden",
+        Nested:         []map[string][2]int{{"p": {1, 2}}},
+        Points:         map[pointKey]string{{1, 2}: "a"},
+        private:        1,
+    }
+    res, err := Normalize(r)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := `{"addr":"127.0.0.1","blob":"aGVsbG8=","count":"3","empty":null,"name":"x",` +
+        `"nested":[{"p":[1,2]}],"note":"inner","points":{"1:2":"a"},"raw":{"big":12345678901234567890,"small":1.5},` +
+        `"scores":{"1":10},"tags":["a","b"],"temp":36.6,"when":"2020-01-02T03:04:05Z"}`
+    if dumpJSON(res, t) != expected {
+        t.Log(dumpJSON(res, t))
+        t.Fail()
+    }
+    // Result must be traversable by queries
+    if v, err := QNumber(res, "nested", 0, "p", 1); err != nil || v != 2 {
+        t.Fail()
+    }
+    if v, err := Q(res, "raw", "small"); err != nil || v != 1.5 {
+        t.Fail()
+    }
+    if v, err := Q(res, "raw", "big"); err != nil || v != json.Number("12345678901234567890") {
+        t.Fail()
+    }
+    if v, err := QString(res, "addr"); err != nil || v != "127.0.0.1" {
+        t.Fail()
+    }
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+    cases := []struct {
+        in  interface{}
+        out interface{}
+    }{
+        {1, 1.0},
+        {int64(1) << 53, float64(int64(1) << 53)},
+        {int64(1)<<53 + 1, json.Number("9007199254740993")},
+        {uint64(math.MaxUint64), json.Number("18446744073709551615")},
+        {float32(0.1), 0.1},
+        {celsius(-1.5), -1.5},
+        {json.Number("0.25"), 0.25},
+        {json.Number("1e400"), json.Number("1e400")},
+    }
+    for _, c := range cases {
+        res, err := Normalize(c.in)
+        if err != nil || res != c.out {
+            t.Errorf("Normalize(%#v) = %#v, %v", c.in, res, err)
+        }
+    }
+}
+
+func TestNormalizeCopies(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    res, err := Normalize(j)
+    if err != nil || !equal(res, j) {
+        t.Fail()
+    }
+    U(&res, "menu", "id", "other")
+    if v, _ := QString(j, "menu", "id"); v != "file" {
+        t.Fail()
+    }
+}
+
+func TestNormalizeErrors(t *testing.T) {
+    var terr TypeError
+    _, err := Normalize(map[string]interface{}{"a": []interface{}{1, func() {}}})
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "a[1]" || terr.Kind() != "func()" {
+        t.Fail()
+    }
+    _, err = Normalize([]interface{}{math.NaN()})
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = Normalize(struct{ C complex128 }{1i})
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "C" {
+        t.Fail()
+    }
+    _, err = Normalize(map[float64]int{1: 1})
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = Normalize(json.Number("nope"))
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+
+    n := &normalizeNode{&normalizeNode{}}
+    n.Next.Next = n
+    _, err = Normalize(n)
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "next.next" {
+        t.Fail()
+    }
+    m := map[string]interface{}{}
+    m["self"] = m
+    if _, err = Normalize(m); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    // Shared but acyclic references are fine
+    shared := &normalizeInner{"x"}
+    if _, err = Normalize([]*normalizeInner{shared, shared}); err != nil {
+        t.Fail()
+    }
+}
+
+func TestUNormalize(t *testing.T) {
+    var j interface{}
+    _, err := UNormalize(&j, "a", 0, map[string][]int{"b": {1, 2}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if v, err := QNumber(j, "a", 0, "b", 1); err != nil || v != 2 {
+        t.Fail()
+    }
+    _, err = UNormalize(&j, "a", 0, "c", []interface{}{make(chan int)})
+    var terr TypeError
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "a[0].c[0]" || terr.Depth() != 4 {
+        t.Fail()
+    }
+    if _, err := Q(j, "a", 0, "c"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+    if _, err = UNormalize(&j); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+}
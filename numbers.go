@@ -0,0 +1,388 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "math/big"
+    "strconv"
+    "strings"
+)
+
+// Sentinel error for classification of RangeError with errors.Is().
+var ErrOutOfRange = errors.New("number out of range")
+
+// This error is returned when number does not fit into requested type.
+type RangeError struct {
+    location
+    msg string
+}
+
+func newRangeError(msg string) RangeError {
+    return RangeError{msg: msg}
+}
+
+func (e RangeError) Error() string {
+    return e.msg + e.location.suffix()
+}
+
+// Reports if error matches ErrOutOfRange.
+func (e RangeError) Is(target error) bool {
+    return target == ErrOutOfRange
+}
+
+func (e RangeError) withLocation(l location) error {
+    e.location = l
+    return e
+}
+
+// Reports if string is a number in JSON syntax.
+func validNumber(n string) bool {
+    s := scanner{data: []byte(n)}
+    return n != "" && s.number() == nil && s.pos == len(s.data)
+}
+
+// Parses exact value of json.Number. Malformed number is reported with
+// TypeError, number with exponent too large for big.Rat with RangeError.
+func parseRat(n json.Number) (*big.Rat, error) {
+    if !validNumber(string(n)) {
+        return nil, newTypeError(fmt.Sprintf("Retrieved value %q is not a valid number", string(n)))
+    }
+    if r, ok := new(big.Rat).SetString(string(n)); ok {
+        return r, nil
+    }
+    // Syntax is valid, so only exponent can be out of big.Rat limits
+    mantissa := string(n[:strings.IndexAny(string(n), "eE")])
+    if strings.Trim(mantissa, "-0.") == "" {
+        return new(big.Rat), nil
+    }
+    return nil, newRangeError(fmt.Sprintf("Retrieved value %s is out of range", string(n)))
+}
+
+// Parses exact value of json.Number as integer.
+func parseIntegral(n json.Number) (*big.Int, error) {
+    r, err := parseRat(n)
+    if err != nil {
+        if _, ok := err.(RangeError); ok && strings.Contains(strings.ToLower(string(n)), "e-") {
+            // Tiny number is out of range, but it's not integer anyway
+            return nil, newTypeError(fmt.Sprintf("Retrieved value %s is not an integer", string(n)))
+        }
+        return nil, err
+    }
+    if !r.IsInt() {
+        return nil, newTypeError(fmt.Sprintf("Retrieved value %s is not an integer", string(n)))
+    }
+    return r.Num(), nil
+}
+
+func floatToInt64(f float64) (int64, error) {
+    if math.IsNaN(f) || math.IsInf(f, 0) || math.Trunc(f) != f {
+        return 0, newTypeError(fmt.Sprintf("Retrieved value %v is not an integer", f))
+    }
+    // float64(math.MaxInt64) rounds up to 2^63
+    if f < math.MinInt64 || f >= math.MaxInt64 {
+        return 0, newRangeError(fmt.Sprintf("Retrieved value %v is out of int64 range", f))
+    }
+    return int64(f), nil
+}
+
+func floatToUint64(f float64) (uint64, error) {
+    if math.IsNaN(f) || math.IsInf(f, 0) || math.Trunc(f) != f {
+        return 0, newTypeError(fmt.Sprintf("Retrieved value %v is not an integer", f))
+    }
+    // float64(math.MaxUint64) rounds up to 2^64
+    if f < 0 || f >= math.MaxUint64 {
+        return 0, newRangeError(fmt.Sprintf("Retrieved value %v is out of uint64 range", f))
+    }
+    return uint64(f), nil
+}
+
+// Converts numeric value to int64 checking that it is integral and fits.
+func toInt64(V interface{}) (int64, error) {
+    switch v := V.(type) {
+    case int:
+        return int64(v), nil
+    case int8:
+        return int64(v), nil
+    case int16:
+        return int64(v), nil
+    case int32:
+        return int64(v), nil
+    case int64:
+        return v, nil
+    case uint, uint8, uint16, uint32, uint64, uintptr:
+        u, _ := toUint64(v)
+        if u > math.MaxInt64 {
+            return 0, newRangeError(fmt.Sprintf("Retrieved value %d is out of int64 range", u))
+        }
+        return int64(u), nil
+    case float32:
+        return floatToInt64(float64(v))
+    case float64:
+        return floatToInt64(v)
+    case json.Number:
+        if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+            return i, nil
+        }
+        i, err := parseIntegral(v)
+        if err != nil {
+            return 0, err
+        }
+        if !i.IsInt64() {
+            return 0, newRangeError(fmt.Sprintf("Retrieved value %s is out of int64 range", string(v)))
+        }
+        return i.Int64(), nil
+    default:
+        return 0, newTypeError("Retrieved value is not a number")
+    }
+}
+
+// Converts numeric value to uint64 checking that it is integral and fits.
+func toUint64(V interface{}) (uint64, error) {
+    switch v := V.(type) {
+    case uint:
+        return uint64(v), nil
+    case uint8:
+        return uint64(v), nil
+    case uint16:
+        return uint64(v), nil
+    case uint32:
+        return uint64(v), nil
+    case uint64:
+        return v, nil
+    case uintptr:
+        return uint64(v), nil
+    case int, int8, int16, int32, int64:
+        i, _ := toInt64(v)
+        if i < 0 {
+            return 0, newRangeError(fmt.Sprintf("Retrieved value %d is out of uint64 range", i))
+        }
+        return uint64(i), nil
+    case float32:
+        return floatToUint64(float64(v))
+    case float64:
+        return floatToUint64(v)
+    case json.Number:
+        if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+            return u, nil
+        }
+        i, err := parseIntegral(v)
+        if err != nil {
+            return 0, err
+        }
+        if !i.IsUint64() {
+            return 0, newRangeError(fmt.Sprintf("Retrieved value %s is out of uint64 range", string(v)))
+        }
+        return i.Uint64(), nil
+    default:
+        return 0, newTypeError("Retrieved value is not a number")
+    }
+}
+
+func qInt(V interface{}, bits int, keys []interface{}) (int64, error) {
+    val, err := Q(V, keys...)
+    if err != nil {
+        return 0, err
+    }
+    res, err := toInt64(val)
+    if err == nil && bits < 64 && (res < -1<<(bits-1) || res > 1<<(bits-1)-1) {
+        err = newRangeError(fmt.Sprintf("Retrieved value %d is out of int%d range", res, bits))
+    }
+    if err != nil {
+        return 0, locate(err, keys, len(keys), val)
+    }
+    return res, nil
+}
+
+func qUint(V interface{}, bits int, keys []interface{}) (uint64, error) {
+    val, err := Q(V, keys...)
+    if err != nil {
+        return 0, err
+    }
+    res, err := toUint64(val)
+    if err == nil && bits < 64 && res > 1<<bits-1 {
+        err = newRangeError(fmt.Sprintf("Retrieved value %d is out of uint%d range", res, bits))
+    }
+    if err != nil {
+        return 0, locate(err, keys, len(keys), val)
+    }
+    return res, nil
+}
+
+// Same as Q(), but converts retrieved number to int. TypeError is returned if
+// value is not a number or has fractional part, RangeError is returned if it
+// does not fit into int.
+func QInt(V interface{}, keys ...interface{}) (int, error) {
+    res, err := qInt(V, strconv.IntSize, keys)
+    return int(res), err
+}
+
+// Same as QInt(), but for int32.
+func QInt32(V interface{}, keys ...interface{}) (int32, error) {
+    res, err := qInt(V, 32, keys)
+    return int32(res), err
+}
+
+// Same as QInt(), but for int64.
+func QInt64(V interface{}, keys ...interface{}) (int64, error) {
+    return qInt(V, 64, keys)
+}
+
+// Same as QInt(), but for uint.
+func QUint(V interface{}, keys ...interface{}) (uint, error) {
+    res, err := qUint(V, strconv.IntSize, keys)
+    return uint(res), err
+}
+
+// Same as QInt(), but for uint32.
+func QUint32(V interface{}, keys ...interface{}) (uint32, error) {
+    res, err := qUint(V, 32, keys)
+    return uint32(res), err
+}
+
+// Same as QInt(), but for uint64.
+func QUint64(V interface{}, keys ...interface{}) (uint64, error) {
+    return qUint(V, 64, keys)
+}
+
+// Reports if value is a number of any supported type.
+func isNumber(V interface{}) bool {
+    switch V.(type) {
+    case float64, float32, json.Number,
+        int, int8, int16, int32, int64,
+        uint, uint8, uint16, uint32, uint64, uintptr:
+        return true
+    default:
+        return false
+    }
+}
+
+// Converts numeric value to float64. Integers beyond 2^53 are rounded.
+func toFloat64(V interface{}) (float64, error) {
+    switch v := V.(type) {
+    case float64:
+        return v, nil
+    case float32:
+        return float64(v), nil
+    case int, int8, int16, int32, int64:
+        i, _ := toInt64(v)
+        return float64(i), nil
+    case uint, uint8, uint16, uint32, uint64, uintptr:
+        u, _ := toUint64(v)
+        return float64(u), nil
+    case json.Number:
+        f, err := strconv.ParseFloat(string(v), 64)
+        if err != nil {
+            if errors.Is(err, strconv.ErrRange) {
+                return 0, newRangeError(fmt.Sprintf("Retrieved value %s is out of float64 range", string(v)))
+            }
+            return 0, newTypeError(fmt.Sprintf("Retrieved value %q is not a valid number", string(v)))
+        }
+        return f, nil
+    default:
+        return 0, newTypeError("Retrieved value is not a number")
+    }
+}
+
+// Converts numeric value to json.Number holding its exact decimal
+// representation.
+func toJSONNumber(V interface{}) (json.Number, error) {
+    switch v := V.(type) {
+    case json.Number:
+        if !validNumber(string(v)) {
+            return "", newTypeError(fmt.Sprintf("Retrieved value %q is not a valid number", string(v)))
+        }
+        return v, nil
+    case float64, float32:
+        bits := 64
+        f, _ := toFloat64(v)
+        if _, ok := v.(float32); ok {
+            bits = 32
+        }
+        if math.IsNaN(f) || math.IsInf(f, 0) {
+            return "", newTypeError(fmt.Sprintf("Retrieved value %v is not representable in JSON", f))
+        }
+        return json.Number(strconv.FormatFloat(f, 'g', -1, bits)), nil
+    case int, int8, int16, int32, int64:
+        i, _ := toInt64(v)
+        return json.Number(strconv.FormatInt(i, 10)), nil
+    case uint, uint8, uint16, uint32, uint64, uintptr:
+        u, _ := toUint64(v)
+        return json.Number(strconv.FormatUint(u, 10)), nil
+    default:
+        return "", newTypeError("Retrieved value is not a number")
+    }
+}
+
+// Converts numeric value to exact rational number. Returns nil for values
+// which have no exact representation, like NaN and infinities.
+func toRat(V interface{}) *big.Rat {
+    switch v := V.(type) {
+    case float64, float32:
+        f, _ := toFloat64(v)
+        if math.IsNaN(f) || math.IsInf(f, 0) {
+            return nil
+        }
+        return new(big.Rat).SetFloat64(f)
+    case int, int8, int16, int32, int64:
+        i, _ := toInt64(v)
+        return new(big.Rat).SetInt64(i)
+    case uint, uint8, uint16, uint32, uint64, uintptr:
+        u, _ := toUint64(v)
+        return new(big.Rat).SetInt(new(big.Int).SetUint64(u))
+    case json.Number:
+        r, err := parseRat(v)
+        if err != nil {
+            return nil
+        }
+        return r
+    default:
+        return nil
+    }
+}
+
+// Compares two numbers of any supported types without loss of precision.
+// Returns false as second value if numbers are not comparable.
+func compareNumbers(a, b interface{}) (int, bool) {
+    x, xok := a.(float64)
+    y, yok := b.(float64)
+    if !xok || !yok {
+        ra, rb := toRat(a), toRat(b)
+        if ra != nil && rb != nil {
+            return ra.Cmp(rb), true
+        }
+        var err error
+        if x, err = toFloat64(a); err != nil {
+            return 0, false
+        }
+        if y, err = toFloat64(b); err != nil {
+            return 0, false
+        }
+    }
+    switch {
+    case x < y:
+        return -1, true
+    case x > y:
+        return 1, true
+    case x == y:
+        return 0, true
+    default:
+        return 0, false
+    }
+}
+
+// Same as Q(), but returns retrieved number of any supported type as
+// json.Number holding its exact decimal representation. Use this function
+// to read integers beyond 2^53 without loss of precision.
+func QJSONNumber(V interface{}, keys ...interface{}) (json.Number, error) {
+    val, err := Q(V, keys...)
+    if err != nil {
+        return "", err
+    }
+    res, err := toJSONNumber(val)
+    if err != nil {
+        return "", locate(err, keys, len(keys), val)
+    }
+    return res, nil
+}
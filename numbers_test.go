@@ -0,0 +1,276 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "math"
+    "testing"
+)
+
+func TestQInt(t *testing.T) {
+    j := loadJSON(`{"port": 8080, "ratio": 0.5, "neg": -3, "big": 1e20, "name": "x", "max": 9223372036854775807}`, t)
+    i, err := QInt(j, "port")
+    if err != nil || i != 8080 {
+        t.Fail()
+    }
+    i, err = QInt(j, "neg")
+    if err != nil || i != -3 {
+        t.Fail()
+    }
+    _, err = QInt(j, "ratio")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = QInt(j, "name")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = QInt64(j, "big")
+    var rerr RangeError
+    if !errors.As(err, &rerr) || !errors.Is(err, ErrOutOfRange) || rerr.Kind() != "number" {
+        t.Fail()
+    }
+    // Decoded as float64, so 2^63 is out of range
+    _, err = QInt64(j, "max")
+    if !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    _, err = QInt(j, "missing")
+    if _, ok := err.(KeyError) ; !ok {
+        t.Fail()
+    }
+    _, err = QUint(j, "neg")
+    if !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    u, err := QUint(j, "port")
+    if err != nil || u != 8080 {
+        t.Fail()
+    }
+}
+
+func TestQIntRanges(t *testing.T) {
+    var j interface{}
+    U(&j, "i32max", float64(math.MaxInt32))
+    U(&j, "i32over", float64(math.MaxInt32+1))
+    U(&j, "i32min", float64(math.MinInt32))
+    U(&j, "i32under", float64(math.MinInt32-1))
+    U(&j, "u32max", float64(math.MaxUint32))
+    U(&j, "u32over", float64(math.MaxUint32+1))
+
+    if v, err := QInt32(j, "i32max"); err != nil || v != math.MaxInt32 {
+        t.Fail()
+    }
+    if v, err := QInt32(j, "i32min"); err != nil || v != math.MinInt32 {
+        t.Fail()
+    }
+    if _, err := QInt32(j, "i32over"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err := QInt32(j, "i32under"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if v, err := QUint32(j, "u32max"); err != nil || v != math.MaxUint32 {
+        t.Fail()
+    }
+    if _, err := QUint32(j, "u32over"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if v, err := QUint64(j, "u32over"); err != nil || v != math.MaxUint32+1 {
+        t.Fail()
+    }
+}
+
+func TestQIntNativeTypes(t *testing.T) {
+    var j interface{}
+    U(&j, "int", 8080)
+    U(&j, "int8", int8(-8))
+    U(&j, "uint16", uint16(16))
+    U(&j, "uint64", uint64(math.MaxUint64))
+    U(&j, "float32", float32(2))
+    U(&j, "nan", math.NaN())
+    if v, err := QInt(j, "int"); err != nil || v != 8080 {
+        t.Fail()
+    }
+    if v, err := QInt64(j, "int8"); err != nil || v != -8 {
+        t.Fail()
+    }
+    if v, err := QUint32(j, "uint16"); err != nil || v != 16 {
+        t.Fail()
+    }
+    if v, err := QUint64(j, "uint64"); err != nil || v != math.MaxUint64 {
+        t.Fail()
+    }
+    if _, err := QInt64(j, "uint64"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err := QUint(j, "int8"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if v, err := QInt(j, "float32"); err != nil || v != 2 {
+        t.Fail()
+    }
+    if _, err := QInt(j, "nan"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+}
+
+func TestQIntJSONNumber(t *testing.T) {
+    var j interface{}
+    U(&j, "exact", json.Number("9007199254740993"))
+    U(&j, "exp", json.Number("1e3"))
+    U(&j, "frac", json.Number("1.5"))
+    U(&j, "huge", json.Number("123456789012345678901234567890"))
+    U(&j, "umax", json.Number("18446744073709551615"))
+    U(&j, "bad", json.Number("abc"))
+    if v, err := QInt64(j, "exact"); err != nil || v != 9007199254740993 {
+        t.Fail()
+    }
+    if v, err := QInt(j, "exp"); err != nil || v != 1000 {
+        t.Fail()
+    }
+    if _, err := QInt(j, "frac"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err := QInt64(j, "huge"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err := QUint64(j, "huge"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if v, err := QUint64(j, "umax"); err != nil || v != math.MaxUint64 {
+        t.Fail()
+    }
+    if v, err := QUint64(j, "exp"); err != nil || v != 1000 {
+        t.Fail()
+    }
+    if _, err := QUint64(j, "bad"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err := QInt64(j, "bad"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err := QUint64(j, "missing"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+    // Exponents beyond big.Rat limits
+    if _, err := QInt64(json.Number("1e10000000")); !errors.Is(err, ErrOutOfRange) {
+        t.Error(err)
+    }
+    if _, err := QUint64(json.Number("-2.5E+10000000")); !errors.Is(err, ErrOutOfRange) {
+        t.Error(err)
+    }
+    if _, err := QInt64(json.Number("1e-10000000")); !errors.Is(err, ErrTypeMismatch) {
+        t.Error(err)
+    }
+    if v, err := QInt64(json.Number("0.0e10000000")); err != nil || v != 0 {
+        t.Error(err)
+    }
+    if _, err := QInt64(json.Number("1e")); !errors.Is(err, ErrTypeMismatch) {
+        t.Error(err)
+    }
+    if v, err := QJSONNumber(json.Number("1e10000000")); err != nil || v != "1e10000000" {
+        t.Error(err)
+    }
+}
+
+func TestQNumberTypes(t *testing.T) {
+    var j interface{}
+    U(&j, "int", 8080)
+    U(&j, "uint8", uint8(255))
+    U(&j, "float32", float32(0.5))
+    U(&j, "number", json.Number("1.25"))
+    U(&j, "huge", json.Number("1e400"))
+    U(&j, "bad", json.Number("x"))
+    cases := map[string]float64{"int": 8080, "uint8": 255, "float32": 0.5, "number": 1.25}
+    for key, ref := range cases {
+        if v, err := QNumber(j, key); err != nil || v != ref {
+            t.Errorf("QNumber(%q) = %v, %v", key, v, err)
+        }
+    }
+    if _, err := QNumber(j, "huge"); !errors.Is(err, ErrOutOfRange) {
+        t.Fail()
+    }
+    if _, err := QNumber(j, "bad"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if v, err := QNumberOr(j, 1, "int"); err != nil || v != 8080 {
+        t.Fail()
+    }
+}
+
+func TestQJSONNumber(t *testing.T) {
+    var j interface{}
+    U(&j, "int64", int64(math.MaxInt64))
+    U(&j, "uint64", uint64(math.MaxUint64))
+    U(&j, "float", 0.1)
+    U(&j, "float32", float32(0.1))
+    U(&j, "number", json.Number("9007199254740993"))
+    U(&j, "inf", math.Inf(1))
+    U(&j, "bad", json.Number("x"))
+    U(&j, "str", "1")
+    cases := map[string]json.Number{
+        "int64":   "9223372036854775807",
+        "uint64":  "18446744073709551615",
+        "float":   "0.1",
+        "float32": "0.1",
+        "number":  "9007199254740993",
+    }
+    for key, ref := range cases {
+        if v, err := QJSONNumber(j, key); err != nil || v != ref {
+            t.Errorf("QJSONNumber(%q) = %v, %v", key, v, err)
+        }
+    }
+    for _, key := range []string{"inf", "bad", "str"} {
+        if _, err := QJSONNumber(j, key); !errors.Is(err, ErrTypeMismatch) {
+            t.Errorf("QJSONNumber(%q) error = %v", key, err)
+        }
+    }
+    if _, err := QJSONNumber(j, "missing"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+}
+
+func TestNumericEquality(t *testing.T) {
+    if !equal(1., 1) || !equal(uint8(1), json.Number("1.0")) || !equal(float32(0.5), json.Number("5e-1")) {
+        t.Fail()
+    }
+    if equal(json.Number("9007199254740993"), float64(9007199254740992)) {
+        t.Fail()
+    }
+    if equal(math.NaN(), math.NaN()) || equal(1, "1") || equal(json.Number("x"), json.Number("x")) {
+        t.Fail()
+    }
+    if !equal(math.Inf(1), float32(math.Inf(1))) || equal(math.Inf(1), json.Number("1e400")) {
+        t.Fail()
+    }
+    if c, ok := compareNumbers(int64(-1), uint64(math.MaxUint64)); !ok || c != -1 {
+        t.Fail()
+    }
+
+    a := loadJSON(`{"a": [1, 2], "b": 3}`, t)
+    var b interface{}
+    U(&b, "a", []interface{}{json.Number("1"), 2})
+    U(&b, "b", uint(3))
+    if patch := Diff(a, b); len(patch) != 0 {
+        t.Fail()
+    }
+    if kindOf(json.Number("1")) != "number" || kindOf(3) != "number" {
+        t.Fail()
+    }
+}
+
+func TestJSONPathNumbers(t *testing.T) {
+    var j interface{}
+    U(&j, Append, "v", json.Number("9007199254740993"))
+    U(&j, Append, "v", 5)
+    U(&j, Append, "v", 1.5)
+    values := jsonPathValues(t, j, `$[?@.v > 2].v`)
+    if len(values) != 2 || values[0] != json.Number("9007199254740993") || values[1] != 5 {
+        t.Fail()
+    }
+    values = jsonPathValues(t, j, `$[?@.v == 5].v`)
+    if len(values) != 1 {
+        t.Fail()
+    }
+}
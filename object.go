@@ -0,0 +1,246 @@
+package qjson
+
+import (
+    "bytes"
+    "encoding/json"
+)
+
+// JSON object which keeps order of its keys. New keys are appended to the
+// end, replaced values keep their positions. Q(), U(), D(), typed accessors
+// and other functions of this package handle *Object the same way as
+// map[string]interface{}. Subtrees created by U() in place of missing
+// members of *Object use ordered objects too. Use OrderedObjects() option of
+// Parse() to get documents with ordered objects. Nil *Object is treated as
+// null, the same way encoding/json encodes it.
+type Object struct {
+    keys   []string
+    values map[string]interface{}
+}
+
+// Creates empty ordered object.
+func NewObject() *Object {
+    return &Object{values: make(map[string]interface{})}
+}
+
+// Returns number of keys in object.
+func (o *Object) Len() int {
+    if o == nil {
+        return 0
+    }
+    return len(o.keys)
+}
+
+// Returns keys of object in order.
+func (o *Object) Keys() []string {
+    if o == nil {
+        return []string{}
+    }
+    return append([]string{}, o.keys...)
+}
+
+// Returns value of key and reports whether key is present.
+func (o *Object) Get(key string) (interface{}, bool) {
+    if o == nil {
+        return nil, false
+    }
+    v, ok := o.values[key]
+    return v, ok
+}
+
+// Sets value of key. New key is appended to the end of object. Like
+// assignment to nil map, setting key of nil *Object panics.
+func (o *Object) Set(key string, value interface{}) {
+    if o.values == nil {
+        o.values = make(map[string]interface{})
+    }
+    if _, ok := o.values[key]; !ok {
+        o.keys = append(o.keys, key)
+    }
+    o.values[key] = value
+}
+
+// Removes key from object. Returns removed value and reports whether key
+// was present.
+func (o *Object) Delete(key string) (interface{}, bool) {
+    if o == nil {
+        return nil, false
+    }
+    v, ok := o.values[key]
+    if !ok {
+        return nil, false
+    }
+    delete(o.values, key)
+    for i, k := range o.keys {
+        if k == key {
+            o.keys = append(o.keys[:i], o.keys[i+1:]...)
+            break
+        }
+    }
+    return v, true
+}
+
+// Returns object members as map. Map is shared with object and must not be
+// modified.
+func (o *Object) Map() map[string]interface{} {
+    if o == nil {
+        return nil
+    }
+    return o.values
+}
+
+// Encodes object keeping order of keys. Members are not HTML-escaped here,
+// escaping is left to the calling encoder.
+func (o *Object) MarshalJSON() ([]byte, error) {
+    if o == nil {
+        return []byte("null"), nil
+    }
+    var buf bytes.Buffer
+    enc := json.NewEncoder(&buf)
+    enc.SetEscapeHTML(false)
+    buf.WriteByte('{')
+    for i, k := range o.keys {
+        if i > 0 {
+            buf.WriteByte(',')
+        }
+        // Encoder terminates every value with newline
+        if err := enc.Encode(k); err != nil {
+            return nil, err
+        }
+        buf.Truncate(buf.Len() - 1)
+        buf.WriteByte(':')
+        if err := enc.Encode(o.values[k]); err != nil {
+            return nil, err
+        }
+        buf.Truncate(buf.Len() - 1)
+    }
+    buf.WriteByte('}')
+    return buf.Bytes(), nil
+}
+
+// Decodes JSON object. Nested objects are decoded as *Object as well.
+func (o *Object) UnmarshalJSON(data []byte) error {
+    V, err := Parse(data, OrderedObjects(), DisallowTrailingData())
+    if err != nil {
+        return err
+    }
+    res, ok := V.(*Object)
+    if !ok {
+        return newTypeError("JSON value is not an object")
+    }
+    *o = *res
+    return nil
+}
+
+// Returns ordered object held by V. Nil *Object is not reported, since it
+// stands for null.
+func asObject(V interface{}) (*Object, bool) {
+    o, ok := V.(*Object)
+    return o, ok && o != nil
+}
+
+// Reports whether V is null, including nil *Object.
+func isNull(V interface{}) bool {
+    o, ok := V.(*Object)
+    return V == nil || ok && o == nil
+}
+
+// Returns members of object represented either by map or by *Object.
+// Returned map must not be modified.
+func asMap(V interface{}) (map[string]interface{}, bool) {
+    switch v := V.(type) {
+    case map[string]interface{}:
+        return v, true
+    case *Object:
+        if v == nil {
+            return nil, false
+        }
+        if v.values == nil {
+            return map[string]interface{}{}, true
+        }
+        return v.values, true
+    default:
+        return nil, false
+    }
+}
+
+// Converts containers created by s() for n keys into ordered objects.
+func orderPath(V interface{}, n int) interface{} {
+    if n <= 0 {
+        return V
+    }
+    switch v := V.(type) {
+    case map[string]interface{}:
+        o := NewObject()
+        for k, elem := range v {
+            o.Set(k, orderPath(elem, n-1))
+        }
+        return o
+    case []interface{}:
+        for i, elem := range v {
+            if elem != nil {
+                v[i] = orderPath(elem, n-1)
+            }
+        }
+    }
+    return V
+}
+
+// Same as s(), but creates ordered objects.
+func sOrdered(keys ...interface{}) (interface{}, error) {
+    tree, err := s(keys...)
+    if err != nil {
+        return nil, err
+    }
+    return orderPath(tree, len(keys)-1), nil
+}
+
+// Same as s(), but creates ordered objects if ordered is set.
+func build(ordered bool, keys ...interface{}) (interface{}, error) {
+    if ordered {
+        return sOrdered(keys...)
+    }
+    return s(keys...)
+}
+
+// Reports whether array belongs to ordered document, judging by its first
+// object element.
+func orderedArray(a []interface{}) bool {
+    for _, elem := range a {
+        switch elem.(type) {
+        case *Object:
+            return true
+        case map[string]interface{}:
+            return false
+        }
+    }
+    return false
+}
+
+// Same as u(), but follows key k of ordered object.
+func uObject(o *Object, k string, keys []interface{}) (interface{}, error) {
+    old, _ := o.Get(k)
+    if len(keys) == 1 {
+        // Reached path destination
+        o.Set(k, keys[0])
+        return old, nil
+    }
+    // Follow next container
+    if isNull(old) {
+        // Recreate subtree
+        tree, err := sOrdered(keys...)
+        if err != nil {
+            return nil, nested(err)
+        }
+        o.Set(k, tree)
+        return nil, nil
+    }
+    res, err := u(old, true, keys...)
+    if size, ok := err.(sliceResizeNeeded); ok {
+        // Handle slice resize
+        old = resizeSlice(old, uint64(size))
+        o.Set(k, old)
+        // Retry with resized array
+        res, err = u(old, true, keys...)
+    }
+    return res, nested(err)
+}
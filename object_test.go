@@ -0,0 +1,277 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+const ORDERED = `{"z": 1, "a": {"y": [true, {"c": 1, "b": 2}], "x": null}, "m": "s"}`
+
+func loadOrdered(data string, t *testing.T) interface{} {
+    j, err := Parse([]byte(data), OrderedObjects())
+    if err != nil {
+        t.FailNow()
+    }
+    return j
+}
+
+func TestObjectBasics(t *testing.T) {
+    var o Object
+    o.Set("b", 1)
+    o.Set("a", 2)
+    o.Set("b", 3)
+    if !reflect.DeepEqual(o.Keys(), []string{"b", "a"}) || o.Len() != 2 {
+        t.Fail()
+    }
+    if v, ok := o.Get("b"); !ok || v != 3 {
+        t.Fail()
+    }
+    if v, ok := o.Delete("b"); !ok || v != 3 {
+        t.Fail()
+    }
+    if _, ok := o.Delete("b"); ok {
+        t.Fail()
+    }
+    o.Set("c", nil)
+    if dumpJSON(&o, t) != `{"a":2,"c":null}` {
+        t.Fail()
+    }
+    if err := json.Unmarshal([]byte(ORDERED), &o); err != nil || dumpJSON(&o, t) != `{"z":1,"a":{"y":[true,{"c":1,"b":2}],"x":null},"m":"s"}` {
+        t.Fail()
+    }
+    if err := json.Unmarshal([]byte(`[]`), &o); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+}
+
+func TestOrderedRoundTrip(t *testing.T) {
+    j := loadOrdered(ORDERED, t)
+    if dumpJSON(j, t) != `{"z":1,"a":{"y":[true,{"c":1,"b":2}],"x":null},"m":"s"}` {
+        t.Fail()
+    }
+    if !equal(j, loadJSON(ORDERED, t)) || !equal(loadJSON(ORDERED, t), j) {
+        t.Fail()
+    }
+    c := deepCopy(j)
+    U(&c, "a", "y", 1, "b", 5)
+    if v, _ := QNumber(j, "a", "y", 1, "b"); v != 2 {
+        t.Fail()
+    }
+    if dumpJSON(c, t) != `{"z":1,"a":{"y":[true,{"c":1,"b":5}],"x":null},"m":"s"}` {
+        t.Fail()
+    }
+    // HTML escaping is decided by calling encoder
+    h := loadOrdered(`{"<a>": "<b>"}`, t)
+    if dumpJSON(h, t) != `{"\u003ca\u003e":"\u003cb\u003e"}` {
+        t.Fail()
+    }
+    if string(encodeJSON(h, "", "")) != `{"<a>":"<b>"}` {
+        t.Fail()
+    }
+}
+
+func TestOrderedQueryUpdate(t *testing.T) {
+    j := loadOrdered(ORDERED, t)
+    if v, err := QBool(j, "a", "y", 0); err != nil || !v {
+        t.Fail()
+    }
+    if err := QNull(j, "a", "x"); err != nil {
+        t.Fail()
+    }
+    if m, err := QObject(j, "a", "y", 1); err != nil || len(m) != 2 {
+        t.Fail()
+    }
+    if o, err := QOrderedObject(j, "a"); err != nil || !reflect.DeepEqual(o.Keys(), []string{"y", "x"}) {
+        t.Fail()
+    }
+    if _, err := QOrderedObject(j, "m"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    var kerr KeyError
+    if _, err := Q(j, "a", "w"); !errors.As(err, &kerr) || kerr.Kind() != "object" {
+        t.Fail()
+    }
+
+    U(&j, "b", "new")
+    U(&j, "a", "x", "q", Append, "r", 1)
+    U(&j, "a", "y", 3, "s")
+    U(&j, "z", 2)
+    if dumpJSON(j, t) != `{"z":2,"a":{"y":[true,{"c":1,"b":2},null,"s"],"x":{"q":[{"r":1}]}},"m":"s","b":"new"}` {
+        t.Fail()
+    }
+    // Subtrees created by U() are ordered
+    U(&j, "a", "x", "p", 0)
+    if dumpJSON(j, t) != `{"z":2,"a":{"y":[true,{"c":1,"b":2},null,"s"],"x":{"q":[{"r":1}],"p":0}},"m":"s","b":"new"}` {
+        t.Fail()
+    }
+    // Array slots within ordered document are filled with ordered objects
+    k := loadOrdered(ORDERED, t)
+    U(&k, "a", "y", 3, "y", 1)
+    U(&k, "a", "y", 3, "b", 1)
+    U(&k, "a", "y", 3, "a", 1)
+    U(&k, "a", "y", Append, "k", 0, "v", 1)
+    if v, _ := Q(k, "a", "y"); dumpJSON(v, t) != `[true,{"c":1,"b":2},null,{"y":1,"b":1,"a":1},{"k":[{"v":1}]}]` {
+        t.Errorf("unexpected result: %s", dumpJSON(v, t))
+    }
+    root := loadOrdered(`[{"a": 1}, null]`, t)
+    U(&root, 1, "z", 1)
+    U(&root, 1, "a", 1)
+    if dumpJSON(root, t) != `[{"a":1},{"z":1,"a":1}]` {
+        t.Fail()
+    }
+    var empty interface{}
+    UOrdered(&empty, "z", "y", 1)
+    UOrdered(&empty, "a", 1)
+    U(&empty, "z", "x", 1)
+    if dumpJSON(empty, t) != `{"z":{"y":1,"x":1},"a":1}` {
+        t.Fail()
+    }
+    if _, err := D(&j, "z"); err != nil {
+        t.Fail()
+    }
+    if _, err := D(&j, "z"); !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+    if _, err := Pop(&j, "a", "y"); err != nil {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":{"y":[true,{"c":1,"b":2},null],"x":{"q":[{"r":1}],"p":0}},"m":"s","b":"new"}` {
+        t.Fail()
+    }
+}
+
+func TestOrderedIntegration(t *testing.T) {
+    j := loadOrdered(ORDERED, t)
+    matches, err := QAll(j, Descend, Any)
+    if err != nil || len(matches) != 9 || matches[0].NormalizedPath() != "$['z']" || matches[1].NormalizedPath() != "$['a']" {
+        t.Fail()
+    }
+    if v := MustCompileJSONPath("$.a.y[1].*").Query(j); len(v) != 2 || v[0].Value != 1.0 {
+        t.Fail()
+    }
+    if v, err := QPointer(j, "/a/y/1/c"); err != nil || v != 1.0 {
+        t.Fail()
+    }
+
+    patch := Patch{{Op: "add", Path: "/a/w", Value: 1}, {Op: "move", From: "/z", Path: "/zz"}}
+    if err := ApplyPatch(&j, patch); err != nil {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":{"y":[true,{"c":1,"b":2}],"x":null,"w":1},"m":"s","zz":1}` {
+        t.Fail()
+    }
+    if len(Diff(j, loadJSON(dumpJSON(j, t), t))) != 0 {
+        t.Fail()
+    }
+    merged := MergePatch(j, loadOrdered(`{"m": null, "n": {"k": 1, "j": 2}}`, t))
+    if dumpJSON(merged, t) != `{"a":{"y":[true,{"c":1,"b":2}],"x":null,"w":1},"zz":1,"n":{"k":1,"j":2}}` {
+        t.Fail()
+    }
+
+    var dst struct {
+        A struct {
+            Y []interface{} `json:"y"`
+        } `json:"a"`
+        M map[string]interface{} `json:"-"`
+    }
+    if err := QInto(j, &dst); err != nil || len(dst.A.Y) != 2 {
+        t.Fail()
+    }
+    n, err := Normalize(map[string]interface{}{"o": j})
+    if err != nil || dumpJSON(n, t) != `{"o":`+dumpJSON(j, t)+`}` {
+        t.Fail()
+    }
+}
+
+func TestNilObject(t *testing.T) {
+    var o *Object
+    if o.Len() != 0 || len(o.Keys()) != 0 || o.Map() != nil {
+        t.Fail()
+    }
+    if _, ok := o.Get("a"); ok {
+        t.Fail()
+    }
+    if _, ok := o.Delete("a"); ok {
+        t.Fail()
+    }
+    if data, err := json.Marshal(map[string]interface{}{"x": o}); err != nil || string(data) != `{"x":null}` {
+        t.Fail()
+    }
+    if data, err := o.MarshalJSON(); err != nil || string(data) != `null` {
+        t.Fail()
+    }
+
+    // Nil object is null for every entry point
+    var terr TypeError
+    j := map[string]interface{}{"x": o}
+    if _, err := Q(j, "x", "y"); !errors.As(err, &terr) || terr.Kind() != "null" || terr.Depth() != 1 {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if err := QNull(j, "x"); err != nil {
+        t.Error(err)
+    }
+    if _, err := QObject(j, "x"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err := QOrderedObject(j, "x"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err := QPointer(j, "/x/y"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if matches, err := QAll(j, "x", Any); err != nil || len(matches) != 0 {
+        t.Fail()
+    }
+    if matches, err := QJSONPath(j, `$[?length(@) == 0]`); err != nil || len(matches) != 0 {
+        t.Fail()
+    }
+    var dst struct {
+        X map[string]int `json:"x"`
+    }
+    dst.X = map[string]int{"a": 1}
+    if err := QInto(j, &dst); err != nil || dst.X != nil {
+        t.Fail()
+    }
+    if !equal(j, map[string]interface{}{"x": nil}) || len(Diff(j, loadJSON(`{"x": null}`, t))) != 0 {
+        t.Fail()
+    }
+    if c := deepCopy(j).(map[string]interface{}); c["x"] != nil {
+        t.Fail()
+    }
+    if n, err := Normalize(j); err != nil || dumpJSON(n, t) != `{"x":null}` {
+        t.Fail()
+    }
+    if res := MergePatch(loadJSON(`{"x": 1, "y": 2}`, t), j); dumpJSON(res, t) != `{"y":2}` {
+        t.Fail()
+    }
+    if res := MergePatch(o, map[string]interface{}{"a": 1}); dumpJSON(res, t) != `{"a":1}` {
+        t.Fail()
+    }
+    var V interface{} = map[string]interface{}{"x": o}
+    if _, err := D(&V, "x", "y"); !errors.As(err, &terr) {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if err := ApplyPatch(&V, Patch{{Op: "add", Path: "/x/y", Value: 1}}); !errors.Is(err, ErrTypeMismatch) {
+        t.Errorf("unexpected error: %v", err)
+    }
+
+    // Null is replaced on update
+    if _, err := U(&V, "x", "y", 1); err != nil || dumpJSON(V, t) != `{"x":{"y":1}}` {
+        t.Errorf("unexpected result: %s, %v", dumpJSON(V, t), err)
+    }
+    V = o
+    if _, err := U(&V, "a", 1); err != nil || dumpJSON(V, t) != `{"a":1}` {
+        t.Errorf("unexpected result: %s, %v", dumpJSON(V, t), err)
+    }
+    V = loadOrdered(`{"b": 1, "a": null}`, t)
+    U(&V, "a", o)
+    if _, err := U(&V, "a", "c", 2); err != nil || dumpJSON(V, t) != `{"b":1,"a":{"c":2}}` {
+        t.Errorf("unexpected result: %s, %v", dumpJSON(V, t), err)
+    }
+    V = []interface{}{o}
+    if _, err := U(&V, 0, 1, true); err != nil || dumpJSON(V, t) != `[[null,true]]` {
+        t.Errorf("unexpected result: %s, %v", dumpJSON(V, t), err)
+    }
+}
@@ -0,0 +1,313 @@
+package qjson
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// Sentinel errors for classification of ParseError with errors.Is().
+var (
+    ErrSyntax        = errors.New("invalid JSON")
+    ErrLimitExceeded = errors.New("parse limit exceeded")
+    ErrDuplicateKey  = errors.New("duplicate key")
+)
+
+// This error is returned when input can't be parsed. Offset points to the
+// byte in input where problem was detected, path refers to the value
+// which was parsed at the moment.
+type ParseError struct {
+    offset int64
+    path   []interface{}
+    msg    string
+    kind   error
+}
+
+func (e ParseError) Error() string {
+    if len(e.path) == 0 {
+        return fmt.Sprintf("Parse error at offset %d: %s", e.offset, e.msg)
+    }
+    return fmt.Sprintf("Parse error at offset %d (path %q): %s", e.offset, FormatPath(e.path...), e.msg)
+}
+
+// Returns offset of input byte where error was detected.
+func (e ParseError) Offset() int64 {
+    return e.offset
+}
+
+// Returns path of value being parsed when error was detected.
+func (e ParseError) Path() []interface{} {
+    return append([]interface{}{}, e.path...)
+}
+
+// Returns ErrSyntax, ErrLimitExceeded, ErrDuplicateKey or error returned by
+// underlying reader.
+func (e ParseError) Unwrap() error {
+    return e.kind
+}
+
+type parseOptions struct {
+    useNumber        bool
+    noTrailing       bool
+    rejectDuplicates bool
+    ordered          bool
+    maxSize          int64
+    maxDepth         int
+}
+
+// Option of Parse(), ParseReader(), ParseString() and their relaxed
+// counterparts.
+type ParseOption func(*parseOptions)
+
+// Keeps numbers as json.Number instead of converting them to float64.
+func UseNumber() ParseOption {
+    return func(o *parseOptions) {
+        o.useNumber = true
+    }
+}
+
+// Rejects input which has anything but whitespace after parsed value.
+func DisallowTrailingData() ParseOption {
+    return func(o *parseOptions) {
+        o.noTrailing = true
+    }
+}
+
+// Rejects input longer than n bytes. ParseReader() stops reading input
+// once limit is exceeded.
+func MaxSize(n int64) ParseOption {
+    return func(o *parseOptions) {
+        o.maxSize = n
+    }
+}
+
+// Rejects input with objects and arrays nested deeper than n levels.
+// Top-level container has depth 1.
+func MaxDepth(n int) ParseOption {
+    return func(o *parseOptions) {
+        o.maxDepth = n
+    }
+}
+
+// Rejects objects with repeated keys. By default last value wins, like in
+// json.Unmarshal().
+func RejectDuplicateKeys() ParseOption {
+    return func(o *parseOptions) {
+        o.rejectDuplicates = true
+    }
+}
+
+// Decodes objects as *Object keeping order of keys.
+func OrderedObjects() ParseOption {
+    return func(o *parseOptions) {
+        o.ordered = true
+    }
+}
+
+// Parses JSON into tree of map[string]interface{} (or *Object),
+// []interface{}, string, float64 (or json.Number), bool and nil values
+// suitable for Q() and U().
+// Parsing problems are reported with ParseError.
+func Parse(data []byte, opts ...ParseOption) (interface{}, error) {
+    return ParseReader(bytes.NewReader(data), opts...)
+}
+
+// Same as Parse(), but accepts string.
+func ParseString(data string, opts ...ParseOption) (interface{}, error) {
+    return ParseReader(strings.NewReader(data), opts...)
+}
+
+// Same as Parse(), but reads input from r. Without DisallowTrailingData()
+// option r may be read past the end of parsed value.
+func ParseReader(r io.Reader, opts ...ParseOption) (interface{}, error) {
+    p := &parser{}
+    for _, opt := range opts {
+        opt(&p.opts)
+    }
+    if p.opts.maxSize > 0 {
+        p.limiter = &sizeLimiter{r: r, max: p.opts.maxSize}
+        r = p.limiter
+    }
+    p.dec = json.NewDecoder(r)
+    p.dec.UseNumber()
+    return p.parse()
+}
+
+var errSizeExceeded = errors.New("input size limit exceeded")
+
+// Fails reading once more than max bytes were read.
+type sizeLimiter struct {
+    r   io.Reader
+    n   int64
+    max int64
+}
+
+func (l *sizeLimiter) Read(b []byte) (int, error) {
+    if l.n > l.max {
+        return 0, errSizeExceeded
+    }
+    if int64(len(b)) > l.max-l.n+1 {
+        b = b[:l.max-l.n+1]
+    }
+    n, err := l.r.Read(b)
+    l.n += int64(n)
+    return n, err
+}
+
+type parser struct {
+    opts    parseOptions
+    dec     *json.Decoder
+    limiter *sizeLimiter
+    path    []interface{}
+}
+
+func (p *parser) fail(kind error, format string, args ...interface{}) error {
+    return ParseError{
+        offset: p.dec.InputOffset(),
+        path:   append([]interface{}{}, p.path...),
+        msg:    fmt.Sprintf(format, args...),
+        kind:   kind,
+    }
+}
+
+// Converts decoder error to ParseError.
+func (p *parser) wrap(err error) error {
+    var serr *json.SyntaxError
+    switch {
+    case p.limiter != nil && p.limiter.n > p.limiter.max:
+        // Decoder reads ahead, so its offset is meaningless here
+        e := p.fail(ErrLimitExceeded, "Input is longer than %d bytes", p.opts.maxSize).(ParseError)
+        e.offset = p.opts.maxSize
+        return e
+    case errors.As(err, &serr):
+        e := p.fail(ErrSyntax, "%s", serr.Error()).(ParseError)
+        e.offset = serr.Offset
+        return e
+    case err == io.EOF || err == io.ErrUnexpectedEOF:
+        return p.fail(ErrSyntax, "Unexpected end of input")
+    default:
+        return p.fail(err, "%s", err.Error())
+    }
+}
+
+func (p *parser) token() (json.Token, error) {
+    tok, err := p.dec.Token()
+    if err != nil {
+        return nil, p.wrap(err)
+    }
+    if p.limiter != nil && p.limiter.n > p.limiter.max {
+        return nil, p.wrap(errSizeExceeded)
+    }
+    return tok, nil
+}
+
+func (p *parser) parse() (interface{}, error) {
+    tok, err := p.token()
+    if err != nil {
+        return nil, err
+    }
+    res, err := p.value(tok, 0)
+    if err != nil {
+        return nil, err
+    }
+    if p.opts.noTrailing {
+        if _, err := p.dec.Token(); err != io.EOF {
+            if err != nil && p.limiter != nil && p.limiter.n > p.limiter.max {
+                return nil, p.wrap(err)
+            }
+            return nil, p.fail(ErrSyntax, "Unexpected data after top-level value")
+        }
+    }
+    return res, nil
+}
+
+func (p *parser) value(tok json.Token, depth int) (interface{}, error) {
+    switch t := tok.(type) {
+    case json.Delim:
+        depth++
+        if p.opts.maxDepth > 0 && depth > p.opts.maxDepth {
+            return nil, p.fail(ErrLimitExceeded, "Nesting depth exceeds %d", p.opts.maxDepth)
+        }
+        if t == '{' {
+            return p.object(depth)
+        }
+        return p.array(depth)
+    case json.Number:
+        if p.opts.useNumber {
+            return t, nil
+        }
+        f, err := strconv.ParseFloat(string(t), 64)
+        if err != nil {
+            return nil, p.fail(ErrSyntax, "Number %s is out of float64 range", string(t))
+        }
+        return f, nil
+    default:
+        return t, nil
+    }
+}
+
+func (p *parser) object(depth int) (interface{}, error) {
+    m := make(map[string]interface{})
+    var o *Object
+    if p.opts.ordered {
+        o = &Object{values: m}
+    }
+    for p.dec.More() {
+        tok, err := p.token()
+        if err != nil {
+            return nil, err
+        }
+        key := tok.(string)
+        if _, ok := m[key]; ok && p.opts.rejectDuplicates {
+            return nil, p.fail(ErrDuplicateKey, "Duplicate key %q", key)
+        }
+        p.path = append(p.path, key)
+        if tok, err = p.token(); err != nil {
+            return nil, err
+        }
+        elem, err := p.value(tok, depth)
+        if err != nil {
+            return nil, err
+        }
+        p.path = p.path[:len(p.path)-1]
+        if o != nil {
+            o.Set(key, elem)
+        } else {
+            m[key] = elem
+        }
+    }
+    // Consume closing delimiter
+    if _, err := p.token(); err != nil {
+        return nil, err
+    }
+    if o != nil {
+        return o, nil
+    }
+    return m, nil
+}
+
+func (p *parser) array(depth int) (interface{}, error) {
+    a := make([]interface{}, 0)
+    for p.dec.More() {
+        p.path = append(p.path, len(a))
+        tok, err := p.token()
+        if err != nil {
+            return nil, err
+        }
+        elem, err := p.value(tok, depth)
+        if err != nil {
+            return nil, err
+        }
+        p.path = p.path[:len(p.path)-1]
+        a = append(a, elem)
+    }
+    // Consume closing delimiter
+    if _, err := p.token(); err != nil {
+        return nil, err
+    }
+    return a, nil
+}
@@ -0,0 +1,101 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "io"
+    "strings"
+    "testing"
+    "testing/iotest"
+)
+
+func TestParse(t *testing.T) {
+    j, err := Parse([]byte(EXAMPLE2))
+    if err != nil || !equal(j, loadJSON(EXAMPLE2, t)) {
+        t.Fail()
+    }
+    j, err = ParseString(`{"a": [1, 2.5, "x", true, null, {}, []]}`)
+    if err != nil || dumpJSON(j, t) != `{"a":[1,2.5,"x",true,null,{},[]]}` {
+        t.Fail()
+    }
+    if v, err := Q(j, "a", 0); err != nil || v != 1.0 {
+        t.Fail()
+    }
+    j, err = ParseString(`{"big": 12345678901234567890}`, UseNumber())
+    if v, err := Q(j, "big"); err != nil || v != json.Number("12345678901234567890") {
+        t.Fail()
+    }
+    // Trailing data is ignored by default
+    j, err = ParseString(`[1] [2]`)
+    if err != nil || dumpJSON(j, t) != `[1]` {
+        t.Fail()
+    }
+    j, err = ParseReader(strings.NewReader("\"abc\"\n"), DisallowTrailingData())
+    if err != nil || j != "abc" {
+        t.Fail()
+    }
+    // Duplicate keys are resolved like json.Unmarshal() does by default
+    j, err = ParseString(`{"a": 1, "a": 2}`)
+    if err != nil || dumpJSON(j, t) != `{"a":2}` {
+        t.Fail()
+    }
+}
+
+func TestParseErrors(t *testing.T) {
+    var perr ParseError
+    _, err := ParseString(`{"a": [1, }`)
+    if !errors.As(err, &perr) || !errors.Is(err, ErrSyntax) || perr.Offset() != 9 ||
+        FormatPath(perr.Path()...) != "a[1]" {
+        t.Fail()
+    }
+    for _, input := range []string{``, `   `, `{"a": `, `[1, 2`, `{"a" 1}`, `1e400`} {
+        if _, err = ParseString(input); !errors.Is(err, ErrSyntax) {
+            t.Errorf("input %q: %v", input, err)
+        }
+    }
+    _, err = ParseString(`[1] [2]`, DisallowTrailingData())
+    if !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+    _, err = ParseString(`[1] x`, DisallowTrailingData())
+    if !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+
+    _, err = ParseString(`{"a": {"b": 1, "b": 2}}`, RejectDuplicateKeys())
+    if !errors.As(err, &perr) || !errors.Is(err, ErrDuplicateKey) || FormatPath(perr.Path()...) != "a" {
+        t.Fail()
+    }
+
+    if _, err = ParseString(`[[[1]]]`, MaxDepth(3)); err != nil {
+        t.Fail()
+    }
+    _, err = ParseString(`{"a": [[1]]}`, MaxDepth(2))
+    if !errors.As(err, &perr) || !errors.Is(err, ErrLimitExceeded) || FormatPath(perr.Path()...) != "a[0]" {
+        t.Fail()
+    }
+
+    if _, err = ParseString(`[1, 2]`, MaxSize(6)); err != nil {
+        t.Fail()
+    }
+    if _, err = ParseString(`[1, 2] `, MaxSize(6)); !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    r := iotest.OneByteReader(strings.NewReader(`"` + strings.Repeat("x", 1000) + `"`))
+    if _, err = ParseReader(r, MaxSize(100)); !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    _, err = ParseString(`[`+strings.Repeat(`1, `, 1000)+`1]`, MaxSize(100))
+    if !errors.As(err, &perr) || !errors.Is(err, ErrLimitExceeded) || perr.Offset() != 100 {
+        t.Errorf("unexpected error: %v", err)
+    }
+
+    r = iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader(`[1, 2]`)))
+    if _, err = ParseReader(r); !errors.Is(err, iotest.ErrTimeout) {
+        t.Fail()
+    }
+    r = io.MultiReader(strings.NewReader(`[1,`), iotest.ErrReader(io.ErrClosedPipe))
+    if _, err = ParseReader(r); !errors.Is(err, io.ErrClosedPipe) {
+        t.Fail()
+    }
+}
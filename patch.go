@@ -0,0 +1,269 @@
+package qjson
+
+import (
+    "errors"
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// Single RFC 6902 JSON Patch operation. From is used only by "move" and
+// "copy" operations, Value is used only by "add", "replace" and "test".
+type Operation struct {
+    Op    string
+    Path  string
+    From  string
+    Value interface{}
+}
+
+// RFC 6902 JSON Patch document.
+type Patch []Operation
+
+// Sentinel error matched by PatchError when "test" operation failed.
+var ErrTestFailed = errors.New("test operation failed")
+
+var errTestMismatch = errors.New("test value mismatch")
+
+// This error is returned when JSON Patch operation can't be applied.
+type PatchError struct {
+    index int
+    op    Operation
+    err   error
+}
+
+func (e PatchError) Error() string {
+    if e.err == nil {
+        return fmt.Sprintf("Patch operation #%d (%s %q) failed: test value mismatch",
+            e.index, e.op.Op, e.op.Path)
+    }
+    return fmt.Sprintf("Patch operation #%d (%s %q) failed: %v",
+        e.index, e.op.Op, e.op.Path, e.err)
+}
+
+// Returns position of failed operation in patch.
+func (e PatchError) Index() int {
+    return e.index
+}
+
+// Returns failed operation.
+func (e PatchError) Operation() Operation {
+    return e.op
+}
+
+// Reports if error matches ErrTestFailed.
+func (e PatchError) Is(target error) bool {
+    return e.err == nil && target == ErrTestFailed
+}
+
+// Returns underlying error or nil if "test" operation failed.
+func (e PatchError) Unwrap() error {
+    return e.err
+}
+
+func (o Operation) MarshalJSON() ([]byte, error) {
+    var res struct {
+        Op    string       `json:"op"`
+        From  *string      `json:"from,omitempty"`
+        Path  string       `json:"path"`
+        Value *interface{} `json:"value,omitempty"`
+    }
+    res.Op = o.Op
+    res.Path = o.Path
+    switch o.Op {
+    case "move", "copy":
+        res.From = &o.From
+    case "add", "replace", "test":
+        res.Value = &o.Value
+    }
+    return json.Marshal(res)
+}
+
+func (o *Operation) UnmarshalJSON(data []byte) error {
+    var V interface{}
+    if err := json.Unmarshal(data, &V); err != nil {
+        return err
+    }
+    op, err := decodeOperation(V)
+    if err != nil {
+        return err
+    }
+    *o = op
+    return nil
+}
+
+func decodeOperation(V interface{}) (Operation, error) {
+    var op Operation
+    m, ok := asMap(V)
+    if !ok {
+        return op, newTypeError("Patch operation is not an object")
+    }
+    var err error
+    if op.Op, err = QString(m, "op"); err != nil {
+        return op, err
+    }
+    if op.Path, err = QString(m, "path"); err != nil {
+        return op, err
+    }
+    switch op.Op {
+    case "add", "replace", "test":
+        if op.Value, err = Q(m, "value"); err != nil {
+            return op, err
+        }
+    case "move", "copy":
+        if op.From, err = QString(m, "from"); err != nil {
+            return op, err
+        }
+    case "remove":
+    default:
+        return op, newArgError(fmt.Sprintf("Unknown patch operation %q", op.Op))
+    }
+    return op, nil
+}
+
+// Converts decoded JSON Patch document into Patch.
+func DecodePatch(V interface{}) (Patch, error) {
+    a, ok := V.([]interface{})
+    if !ok {
+        return nil, newTypeError("Patch is not an array")
+    }
+    res := make(Patch, len(a))
+    for i, elem := range a {
+        op, err := decodeOperation(elem)
+        if err != nil {
+            return nil, err
+        }
+        res[i] = op
+    }
+    return res, nil
+}
+
+// Apply RFC 6902 JSON Patch to JSON.
+// Operations are applied to a copy of document which replaces original only
+// if all operations succeeded, so V is left untouched on error. Returned
+// error is PatchError which wraps error of failed operation.
+func ApplyPatch(V *interface{}, patch Patch) error {
+    if V == nil {
+        return newArgError("nil pointer dereference")
+    }
+    doc := deepCopy(*V)
+    for i, op := range patch {
+        err := applyOperation(&doc, op)
+        if err == errTestMismatch {
+            return PatchError{i, op, nil}
+        }
+        if err != nil {
+            return PatchError{i, op, err}
+        }
+    }
+    *V = doc
+    return nil
+}
+
+// Applies single operation to doc in place. Failed "test" operation is
+// reported with errTestMismatch.
+func applyOperation(doc *interface{}, op Operation) error {
+    switch op.Op {
+    case "add":
+        return patchAdd(doc, op.Path, deepCopy(op.Value))
+    case "remove":
+        return patchRemove(doc, op.Path)
+    case "replace":
+        return patchReplace(doc, op.Path, deepCopy(op.Value))
+    case "move":
+        return patchMove(doc, op.From, op.Path)
+    case "copy":
+        return patchCopy(doc, op.From, op.Path)
+    case "test":
+        val, err := QPointer(*doc, op.Path)
+        if err == nil && !equal(val, op.Value) {
+            return errTestMismatch
+        }
+        return err
+    default:
+        return newArgError(fmt.Sprintf("Unknown patch operation %q", op.Op))
+    }
+}
+
+func patchAdd(doc *interface{}, path string, value interface{}) error {
+    keys, err := PointerKeys(*doc, path)
+    if err != nil {
+        return err
+    }
+    if len(keys) == 0 {
+        *doc = value
+        return nil
+    }
+    l := len(keys)
+    err = apply(doc, keys[:l-1], func(C interface{}) (interface{}, error) {
+        switch c := C.(type) {
+        case map[string]interface{}:
+            c[keys[l-1].(string)] = value
+            return c, nil
+        case *Object:
+            if c == nil {
+                // Nil object is null
+                break
+            }
+            c.Set(keys[l-1].(string), value)
+            return c, nil
+        case []interface{}:
+            idx := keys[l-1].(int)
+            if idx > len(c) {
+                return nil, newIndexError(idx)
+            }
+            c = append(c, nil)
+            copy(c[idx+1:], c[idx:])
+            c[idx] = value
+            return c, nil
+        }
+        return nil, newTypeError("Bad container type: not a map or array")
+    })
+    return withPath(err, keys)
+}
+
+func patchRemove(doc *interface{}, path string) error {
+    keys, err := PointerKeys(*doc, path)
+    if err != nil {
+        return err
+    }
+    _, err = D(doc, keys...)
+    return err
+}
+
+func patchReplace(doc *interface{}, path string, value interface{}) error {
+    keys, err := PointerKeys(*doc, path)
+    if err != nil {
+        return err
+    }
+    err = apply(doc, keys, func(interface{}) (interface{}, error) {
+        return value, nil
+    })
+    return withPath(err, keys)
+}
+
+func patchMove(doc *interface{}, from, path string) error {
+    if from == path {
+        _, err := QPointer(*doc, from)
+        return err
+    }
+    if strings.HasPrefix(path, from+"/") {
+        return newArgError("Can't move value into its own child")
+    }
+    keys, err := PointerKeys(*doc, from)
+    if err != nil {
+        return err
+    }
+    value, err := D(doc, keys...)
+    if err != nil {
+        return err
+    }
+    return patchAdd(doc, path, value)
+}
+
+func patchCopy(doc *interface{}, from, path string) error {
+    value, err := QPointer(*doc, from)
+    if err != nil {
+        return err
+    }
+    return patchAdd(doc, path, deepCopy(value))
+}
@@ -0,0 +1,147 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "testing"
+)
+
+func loadPatch(data string, t *testing.T) Patch {
+    var p Patch
+    if err := json.Unmarshal([]byte(data), &p); err != nil {
+        t.Fatalf("can't load patch: %v", err)
+    }
+    return p
+}
+
+func TestApplyPatch(t *testing.T) {
+    cases := []struct {
+        doc, patch, result string
+    }{
+        {`{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"baz":"qux","foo":"bar"}`},
+        {`{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo":["bar","qux","baz"]}`},
+        {`{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo":"bar"}`},
+        {`{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo":["bar","baz"]}`},
+        {`{"baz": "qux", "foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": "boo"}]`, `{"baz":"boo","foo":"bar"}`},
+        {`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+            `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+            `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+        {`{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+        {`{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+        {`{"foo": "bar"}`, `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+        {`{"foo": {"a": 1}}`, `[{"op": "copy", "from": "/foo", "path": "/bar"}, {"op": "add", "path": "/bar/b", "value": 2}]`, `{"bar":{"a":1,"b":2},"foo":{"a":1}}`},
+        {`{"/": 9, "~1": 10}`, `[{"op": "test", "path": "/~01", "value": 10}, {"op": "remove", "path": "/~1"}]`, `{"~1":10}`},
+        {`{"foo": "bar"}`, `[{"op": "replace", "path": "", "value": [1]}]`, `[1]`},
+        {`{"foo": "bar"}`, `[{"op": "move", "from": "/foo", "path": "/foo"}]`, `{"foo":"bar"}`},
+    }
+    for _, c := range cases {
+        j := loadJSON(c.doc, t)
+        if err := ApplyPatch(&j, loadPatch(c.patch, t)); err != nil {
+            t.Errorf("patch %s failed: %v", c.patch, err)
+            continue
+        }
+        if res := dumpJSON(j, t); res != c.result {
+            t.Errorf("patch %s result = %s", c.patch, res)
+        }
+    }
+}
+
+func TestApplyPatchAtomic(t *testing.T) {
+    cases := []struct {
+        doc, patch string
+        index      int
+    }{
+        {`{"baz": "qux", "foo": ["a", 2, "c"]}`, `[{"op": "add", "path": "/x", "value": 1}, {"op": "test", "path": "/baz", "value": "bar"}]`, 1},
+        {`{"foo": "bar"}`, `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, 0},
+        {`{"foo": [1]}`, `[{"op": "remove", "path": "/foo/0"}, {"op": "add", "path": "/foo/2", "value": 1}]`, 1},
+        {`{"foo": {}}`, `[{"op": "move", "from": "/foo", "path": "/foo/bar"}]`, 0},
+        {`{"foo": {}}`, `[{"op": "replace", "path": "/bar", "value": 1}]`, 0},
+        {`{"foo": {}}`, `[{"op": "remove", "path": ""}]`, 0},
+        {`{"foo": []}`, `[{"op": "remove", "path": "/foo/-"}]`, 0},
+        {`{"foo": []}`, `[{"op": "add", "path": "/foo/bar", "value": 1}]`, 0},
+        {`{"foo": 1}`, `[{"op": "copy", "from": "/bar", "path": "/foo"}]`, 0},
+    }
+    for _, c := range cases {
+        j := loadJSON(c.doc, t)
+        ref := dumpJSON(j, t)
+        err := ApplyPatch(&j, loadPatch(c.patch, t))
+        var perr PatchError
+        if !errors.As(err, &perr) || perr.Index() != c.index {
+            t.Errorf("patch %s error = %v", c.patch, err)
+        }
+        if dumpJSON(j, t) != ref {
+            t.Errorf("patch %s modified document", c.patch)
+        }
+    }
+    j := loadJSON(`{"a": 1}`, t)
+    err := ApplyPatch(&j, Patch{{Op: "test", Path: "/b", Value: 1.}})
+    if !errors.As(err, new(KeyError)) {
+        t.Fail()
+    }
+    err = ApplyPatch(&j, Patch{{Op: "frobnicate", Path: "/a"}})
+    if !errors.As(err, new(ArgError)) {
+        t.Fail()
+    }
+    err = ApplyPatch(nil, nil)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+}
+
+func TestDecodePatch(t *testing.T) {
+    for _, p := range []string{
+        `{}`,
+        `[1]`,
+        `[{"path": "/a"}]`,
+        `[{"op": "add", "path": "/a"}]`,
+        `[{"op": "move", "path": "/a"}]`,
+        `[{"op": "remove"}]`,
+        `[{"op": "frobnicate", "path": "/a"}]`,
+    } {
+        if _, err := DecodePatch(loadJSON(p, t)); err == nil {
+            t.Errorf("DecodePatch(%s) succeeded", p)
+        }
+    }
+    p, err := DecodePatch(loadJSON(`[{"op": "add", "path": "/a", "value": null}]`, t))
+    if err != nil || len(p) != 1 || p[0].Value != nil {
+        t.Fail()
+    }
+}
+
+func TestPatchMarshal(t *testing.T) {
+    p := Patch{
+        {Op: "add", Path: "/a", Value: nil},
+        {Op: "remove", Path: "/b"},
+        {Op: "move", From: "", Path: "/c"},
+    }
+    d, err := json.Marshal(p)
+    if err != nil {
+        t.FailNow()
+    }
+    ref := `[{"op":"add","path":"/a","value":null},{"op":"remove","path":"/b"},{"op":"move","from":"","path":"/c"}]`
+    if string(d) != ref {
+        t.Error(string(d))
+    }
+    if dumpJSON(loadPatch(ref, t), t) != ref {
+        t.Fail()
+    }
+    var op Operation
+    if err := json.Unmarshal([]byte(`[]`), &op); err == nil {
+        t.Fail()
+    }
+    if err := json.Unmarshal([]byte(`{`), &op); err == nil {
+        t.Fail()
+    }
+}
+
+func TestPatchTestFailed(t *testing.T) {
+    j := loadJSON(`{"a": 1}`, t)
+    err := ApplyPatch(&j, Patch{{Op: "test", Path: "/a", Value: 2.}})
+    if !errors.Is(err, ErrTestFailed) || errors.Unwrap(err) != nil {
+        t.Fail()
+    }
+    err = ApplyPatch(&j, Patch{{Op: "test", Path: "/b", Value: 2.}})
+    if errors.Is(err, ErrTestFailed) || !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+}
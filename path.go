@@ -0,0 +1,320 @@
+package qjson
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf16"
+)
+
+// Parses string path into list of keys suitable for Q() and U().
+//
+// Path consists of object keys separated by dots and array indexes enclosed
+// in brackets, e.g. `menu.popup.menuitem[1].value`. Keys containing special
+// characters may be quoted inside brackets (`a["b.c"]` or `a['b.c']`) or
+// have special characters escaped with backslash (`a.b\.c`). Empty string
+// denotes root of document. Bare keys are always strings: `a.0` refers to
+// key "0" of object "a", not to array element. `[-]` stands for Append key,
+// e.g. `a.list[-]`.
+func ParsePath(path string) ([]interface{}, error) {
+    p := &pathParser{src: path}
+    return p.parse()
+}
+
+// Formats list of keys into string path accepted by ParsePath().
+func FormatPath(keys ...interface{}) string {
+    var b strings.Builder
+    for i, key := range keys {
+        switch k := key.(type) {
+        case string:
+            if isBareKey(k) {
+                if i > 0 {
+                    b.WriteByte('.')
+                }
+                b.WriteString(k)
+            } else {
+                b.WriteByte('[')
+                b.WriteString(quoteKey(k))
+                b.WriteByte(']')
+            }
+        case int:
+            b.WriteByte('[')
+            b.WriteString(strconv.Itoa(k))
+            b.WriteByte(']')
+        default:
+            fmt.Fprintf(&b, "[%v]", k)
+        }
+    }
+    return b.String()
+}
+
+func isBareKey(key string) bool {
+    if key == "" {
+        return false
+    }
+    for _, c := range key {
+        switch c {
+        case '.', '[', ']', '\\', '"', '\'':
+            return false
+        }
+        if c < 0x20 {
+            return false
+        }
+    }
+    return true
+}
+
+func quoteKey(key string) string {
+    res, _ := json.Marshal(key)
+    return string(res)
+}
+
+type pathParser struct {
+    src string
+    pos int
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+    return newArgError(fmt.Sprintf("Bad path %q at offset %d: %s",
+        p.src, p.pos, fmt.Sprintf(format, args...)))
+}
+
+func (p *pathParser) parse() ([]interface{}, error) {
+    keys := []interface{}{}
+    if p.src == "" {
+        return keys, nil
+    }
+    if p.src[0] != '[' {
+        key, err := p.bareKey()
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, key)
+    }
+    for p.pos < len(p.src) {
+        switch p.src[p.pos] {
+        case '.':
+            p.pos++
+            key, err := p.bareKey()
+            if err != nil {
+                return nil, err
+            }
+            keys = append(keys, key)
+        case '[':
+            p.pos++
+            key, err := p.bracket()
+            if err != nil {
+                return nil, err
+            }
+            keys = append(keys, key)
+        default:
+            return nil, p.errorf("unexpected character %q", p.src[p.pos])
+        }
+    }
+    return keys, nil
+}
+
+func (p *pathParser) bareKey() (string, error) {
+    var b strings.Builder
+    for p.pos < len(p.src) {
+        c := p.src[p.pos]
+        if c == '.' || c == '[' {
+            break
+        }
+        if c == ']' || c == '"' || c == '\'' {
+            return "", p.errorf("unexpected character %q", c)
+        }
+        if c == '\\' {
+            p.pos++
+            if p.pos >= len(p.src) {
+                return "", p.errorf("unterminated escape sequence")
+            }
+            c = p.src[p.pos]
+        }
+        b.WriteByte(c)
+        p.pos++
+    }
+    if b.Len() == 0 {
+        return "", p.errorf("empty key")
+    }
+    return b.String(), nil
+}
+
+func (p *pathParser) bracket() (interface{}, error) {
+    if p.pos >= len(p.src) {
+        return nil, p.errorf("unterminated bracket")
+    }
+    var key interface{}
+    switch c := p.src[p.pos]; {
+    case c == '"' || c == '\'':
+        p.pos++
+        str, err := p.quoted(c)
+        if err != nil {
+            return nil, err
+        }
+        key = str
+    case c == '-' && strings.HasPrefix(p.src[p.pos:], "-]"):
+        p.pos++
+        key = Append
+    case c == '-' || (c >= '0' && c <= '9'):
+        start := p.pos
+        p.pos++
+        for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+            p.pos++
+        }
+        num := p.src[start:p.pos]
+        idx, err := strconv.Atoi(num)
+        if err != nil {
+            p.pos = start
+            return nil, p.errorf("bad index %q", num)
+        }
+        key = idx
+    default:
+        return nil, p.errorf("unexpected character %q", c)
+    }
+    if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+        return nil, p.errorf("expected ']'")
+    }
+    p.pos++
+    return key, nil
+}
+
+func (p *pathParser) quoted(quote byte) (string, error) {
+    var b strings.Builder
+    for p.pos < len(p.src) {
+        c := p.src[p.pos]
+        p.pos++
+        if c == quote {
+            return b.String(), nil
+        }
+        if c != '\\' {
+            b.WriteByte(c)
+            continue
+        }
+        if p.pos >= len(p.src) {
+            break
+        }
+        c = p.src[p.pos]
+        p.pos++
+        switch c {
+        case 'b':
+            b.WriteByte('\b')
+        case 'f':
+            b.WriteByte('\f')
+        case 'n':
+            b.WriteByte('\n')
+        case 'r':
+            b.WriteByte('\r')
+        case 't':
+            b.WriteByte('\t')
+        case 'u':
+            r, err := p.hex4()
+            if err != nil {
+                return "", err
+            }
+            if utf16.IsSurrogate(r) && strings.HasPrefix(p.src[p.pos:], "\\u") {
+                save := p.pos
+                p.pos += 2
+                r2, err := p.hex4()
+                if err != nil {
+                    return "", err
+                }
+                if dec := utf16.DecodeRune(r, r2); dec != unicode.ReplacementChar {
+                    r = dec
+                } else {
+                    p.pos = save
+                }
+            }
+            b.WriteRune(r)
+        default:
+            b.WriteByte(c)
+        }
+    }
+    return "", p.errorf("unterminated string")
+}
+
+func (p *pathParser) hex4() (rune, error) {
+    if p.pos+4 > len(p.src) {
+        return 0, p.errorf("bad unicode escape")
+    }
+    r, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+    if err != nil {
+        return 0, p.errorf("bad unicode escape")
+    }
+    p.pos += 4
+    return rune(r), nil
+}
+
+// Same as Q(), but accepts path in format of ParsePath().
+func QP(V interface{}, path string) (interface{}, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return nil, err
+    }
+    return Q(V, keys...)
+}
+
+// Same as U(), but accepts path in format of ParsePath().
+func UP(V *interface{}, path string, newval interface{}) (interface{}, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return nil, err
+    }
+    return U(V, append(keys, newval)...)
+}
+
+// Same as QBool(), but accepts path in format of ParsePath().
+func QBoolP(V interface{}, path string) (bool, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return false, err
+    }
+    return QBool(V, keys...)
+}
+
+// Same as QNumber(), but accepts path in format of ParsePath().
+func QNumberP(V interface{}, path string) (float64, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return 0, err
+    }
+    return QNumber(V, keys...)
+}
+
+// Same as QString(), but accepts path in format of ParsePath().
+func QStringP(V interface{}, path string) (string, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return "", err
+    }
+    return QString(V, keys...)
+}
+
+// Same as QList(), but accepts path in format of ParsePath().
+func QListP(V interface{}, path string) ([]interface{}, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return nil, err
+    }
+    return QList(V, keys...)
+}
+
+// Same as QObject(), but accepts path in format of ParsePath().
+func QObjectP(V interface{}, path string) (map[string]interface{}, error) {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return nil, err
+    }
+    return QObject(V, keys...)
+}
+
+// Same as QNull(), but accepts path in format of ParsePath().
+func QNullP(V interface{}, path string) error {
+    keys, err := ParsePath(path)
+    if err != nil {
+        return err
+    }
+    return QNull(V, keys...)
+}
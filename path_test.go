@@ -0,0 +1,107 @@
+package qjson
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestParsePath(t *testing.T) {
+    cases := []struct {
+        path string
+        keys []interface{}
+    }{
+        {``, []interface{}{}},
+        {`a`, []interface{}{"a"}},
+        {`menu.popup.menuitem[1].value`, []interface{}{"menu", "popup", "menuitem", 1, "value"}},
+        {`[0][1].a`, []interface{}{0, 1, "a"}},
+        {`a.0`, []interface{}{"a", "0"}},
+        {`a["b.c"]['d[e]']`, []interface{}{"a", "b.c", "d[e]"}},
+        {`a.b\.c`, []interface{}{"a", "b.c"}},
+        {`a["q\"\\A"]`, []interface{}{"a", "q\"\\A"}},
+        {`a['it\'s']`, []interface{}{"a", "it's"}},
+        {`[-1]`, []interface{}{-1}},
+    }
+    for _, c := range cases {
+        keys, err := ParsePath(c.path)
+        if err != nil || !reflect.DeepEqual(keys, c.keys) {
+            t.Errorf("ParsePath(%q) = %#v, %v", c.path, keys, err)
+        }
+    }
+}
+
+func TestParsePathErrors(t *testing.T) {
+    for _, path := range []string{`.a`, `a..b`, `a.`, `a[`, `a[1`, `a[x]`, `a["b]`, `a]`, `a[--]`, `a[-x]`, `a\`, `a[1]b`} {
+        _, err := ParsePath(path)
+        if _, ok := err.(ArgError) ; !ok {
+            t.Errorf("ParsePath(%q) error = %v", path, err)
+        }
+    }
+}
+
+func TestFormatPath(t *testing.T) {
+    keys := []interface{}{"menu", "popup", "menuitem", 1, "value", "a.b", "", "x\"y"}
+    path := FormatPath(keys...)
+    if path != `menu.popup.menuitem[1].value["a.b"][""]["x\"y"]` {
+        t.Fail()
+    }
+    parsed, err := ParsePath(path)
+    if err != nil || !reflect.DeepEqual(parsed, keys) {
+        t.Fail()
+    }
+    if FormatPath(0, "a") != "[0].a" {
+        t.Fail()
+    }
+    keys = []interface{}{"a", Append, "b", Append}
+    path = FormatPath(keys...)
+    parsed, err = ParsePath(path)
+    if path != "a[-].b[-]" || err != nil || !reflect.DeepEqual(parsed, keys) {
+        t.Fail()
+    }
+}
+
+func TestQP(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    s, err := QStringP(j, "menu.popup.menuitem[1].value")
+    if err != nil || s != "Open" {
+        t.Fail()
+    }
+    l, err := QListP(j, "menu.popup.menuitem")
+    if err != nil || len(l) != 3 {
+        t.Fail()
+    }
+    _, err = QP(j, "menu.nonexistent")
+    if _, ok := err.(KeyError) ; !ok {
+        t.Fail()
+    }
+    _, err = QP(j, "menu..id")
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+}
+
+func TestUP(t *testing.T) {
+    var k interface{}
+    UP(&k, "menu.id", "file")
+    UP(&k, "menu.value", "File")
+    UP(&k, "menu.popup.menuitem[0].value", "New")
+    UP(&k, "menu.popup.menuitem[0].onclick", "CreateNewDoc()")
+    UP(&k, "menu.popup.menuitem[1].value", "Open")
+    UP(&k, "menu.popup.menuitem[1].onclick", "OpenDoc()")
+    UP(&k, "menu.popup.menuitem[2].value", "Close")
+    UP(&k, "menu.popup.menuitem[2].onclick", "CloseDoc()")
+    if dumpJSON(k, t) != dumpJSON(loadJSON(EXAMPLE2, t), t) {
+        t.Fail()
+    }
+    old, err := UP(&k, "menu.id", "edit")
+    if err != nil || old != "file" {
+        t.Fail()
+    }
+    UP(&k, "menu.popup.menuitem[-].value", "Save")
+    if v, err := QStringP(k, "menu.popup.menuitem[3].value"); err != nil || v != "Save" {
+        t.Fail()
+    }
+    _, err = UP(&k, "menu[", nil)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+}
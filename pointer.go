@@ -0,0 +1,148 @@
+package qjson
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Parses RFC 6901 JSON Pointer into list of unescaped reference tokens.
+// Empty pointer refers to whole document and yields empty list.
+func ParsePointer(ptr string) ([]string, error) {
+    if ptr == "" {
+        return []string{}, nil
+    }
+    if ptr[0] != '/' {
+        return nil, newArgError("JSON Pointer must start with \"/\"")
+    }
+    tokens := strings.Split(ptr[1:], "/")
+    for i, token := range tokens {
+        if !strings.Contains(token, "~") {
+            continue
+        }
+        var b strings.Builder
+        for j := 0; j < len(token); j++ {
+            c := token[j]
+            if c != '~' {
+                b.WriteByte(c)
+                continue
+            }
+            j++
+            if j >= len(token) || (token[j] != '0' && token[j] != '1') {
+                return nil, newArgError("Bad escape sequence in JSON Pointer " + strconv.Quote(ptr))
+            }
+            if token[j] == '0' {
+                b.WriteByte('~')
+            } else {
+                b.WriteByte('/')
+            }
+        }
+        tokens[i] = b.String()
+    }
+    return tokens, nil
+}
+
+// Formats list of keys into RFC 6901 JSON Pointer.
+func FormatPointer(keys ...interface{}) string {
+    var b strings.Builder
+    for _, key := range keys {
+        b.WriteByte('/')
+        switch k := key.(type) {
+        case string:
+            b.WriteString(pointerEscaper.Replace(k))
+        case int:
+            b.WriteString(strconv.Itoa(k))
+        default:
+            b.WriteString(pointerEscaper.Replace(fmt.Sprint(k)))
+        }
+    }
+    return b.String()
+}
+
+// Converts JSON Pointer into list of keys suitable for Q() and U(). Since
+// pointer tokens are untyped, they are resolved against document V: tokens
+// addressing arrays become int indexes ("-" becomes index right past the
+// last element) and all other tokens become string keys.
+func PointerKeys(V interface{}, ptr string) ([]interface{}, error) {
+    tokens, err := ParsePointer(ptr)
+    if err != nil {
+        return nil, err
+    }
+    keys := make([]interface{}, 0, len(tokens))
+    cur := V
+    for _, token := range tokens {
+        switch c := cur.(type) {
+        case []interface{}:
+            idx, err := pointerIndex(token, len(c))
+            if err != nil {
+                return nil, err
+            }
+            keys = append(keys, idx)
+            if idx < len(c) {
+                cur = c[idx]
+            } else {
+                cur = nil
+            }
+        case map[string]interface{}, *Object:
+            keys = append(keys, token)
+            m, _ := asMap(c)
+            cur = m[token]
+        default:
+            if n, ok := arrayLen(c); ok {
+                idx, err := pointerIndex(token, n)
+                if err != nil {
+                    return nil, err
+                }
+                keys = append(keys, idx)
+                cur, _ = reflectQ(c, idx)
+            } else {
+                keys = append(keys, token)
+                cur, _ = reflectQ(c, token)
+            }
+        }
+    }
+    return keys, nil
+}
+
+func pointerIndex(token string, length int) (int, error) {
+    if token == "-" {
+        return length, nil
+    }
+    if token == "" || (len(token) > 1 && token[0] == '0') {
+        return 0, newTypeError("Bad array index " + strconv.Quote(token))
+    }
+    for _, c := range token {
+        if c < '0' || c > '9' {
+            return 0, newTypeError("Bad array index " + strconv.Quote(token))
+        }
+    }
+    idx, err := strconv.Atoi(token)
+    if err != nil {
+        return 0, newTypeError("Bad array index " + strconv.Quote(token))
+    }
+    return idx, nil
+}
+
+// Same as Q(), but accepts RFC 6901 JSON Pointer as a path.
+func QPointer(V interface{}, ptr string) (interface{}, error) {
+    keys, err := PointerKeys(V, ptr)
+    if err != nil {
+        return nil, err
+    }
+    return Q(V, keys...)
+}
+
+// Same as U(), but accepts RFC 6901 JSON Pointer as a path. Token "-"
+// appends new element to array.
+func UPointer(V *interface{}, ptr string, newval interface{}) (interface{}, error) {
+    if V == nil {
+        return nil, newArgError("nil pointer dereference")
+    }
+    keys, err := PointerKeys(*V, ptr)
+    if err != nil {
+        return nil, err
+    }
+    return U(V, append(keys, newval)...)
+}
@@ -0,0 +1,92 @@
+package qjson
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestParsePointer(t *testing.T) {
+    cases := []struct {
+        ptr    string
+        tokens []string
+    }{
+        {"", []string{}},
+        {"/", []string{""}},
+        {"/menu/popup/menuitem/1", []string{"menu", "popup", "menuitem", "1"}},
+        {"/a~1b/m~0n/~01", []string{"a/b", "m~n", "~1"}},
+    }
+    for _, c := range cases {
+        tokens, err := ParsePointer(c.ptr)
+        if err != nil || !reflect.DeepEqual(tokens, c.tokens) {
+            t.Errorf("ParsePointer(%q) = %#v, %v", c.ptr, tokens, err)
+        }
+    }
+    for _, ptr := range []string{"a", "/a~", "/a~2"} {
+        if _, err := ParsePointer(ptr); err == nil {
+            t.Errorf("ParsePointer(%q) succeeded", ptr)
+        } else if _, ok := err.(ArgError) ; !ok {
+            t.Fail()
+        }
+    }
+}
+
+func TestFormatPointer(t *testing.T) {
+    if FormatPointer() != "" {
+        t.Fail()
+    }
+    if FormatPointer("a/b", "m~n", 1) != "/a~1b/m~0n/1" {
+        t.Fail()
+    }
+}
+
+func TestQPointer(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    v, err := QPointer(j, "/menu/popup/menuitem/1/value")
+    if err != nil || v != "Open" {
+        t.Fail()
+    }
+    _, err = QPointer(j, "/menu/popup/menuitem/3")
+    if _, ok := err.(IndexError) ; !ok {
+        t.Fail()
+    }
+    _, err = QPointer(j, "/menu/popup/menuitem/-")
+    if _, ok := err.(IndexError) ; !ok {
+        t.Fail()
+    }
+    _, err = QPointer(j, "/menu/popup/menuitem/01")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = QPointer(j, "/menu/nonexistent")
+    if _, ok := err.(KeyError) ; !ok {
+        t.Fail()
+    }
+    j = loadJSON(`{"1": "one", "a/b": true}`, t)
+    v, err = QPointer(j, "/1")
+    if err != nil || v != "one" {
+        t.Fail()
+    }
+    v, err = QPointer(j, "/a~1b")
+    if err != nil || v != true {
+        t.Fail()
+    }
+}
+
+func TestUPointer(t *testing.T) {
+    j := loadJSON(`{"a": [1, 2]}`, t)
+    _, err := UPointer(&j, "/a/-", 3.)
+    if err != nil {
+        t.Fail()
+    }
+    _, err = UPointer(&j, "/b/0", "x")
+    if err != nil {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":[1,2,3],"b":{"0":"x"}}` {
+        t.Fail()
+    }
+    _, err = UPointer(nil, "/a", 1)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+}
@@ -2,6 +2,8 @@
 package qjson
 
 import (
+    "encoding/json"
+    "errors"
     "fmt"
 )
 
@@ -15,63 +17,236 @@ func (e sliceResizeNeeded) Error() string {
     return fmt.Sprintf("Slice needs to be at least %v elements long", int64(e))
 }
 
+// Sentinel errors for classification of failures with errors.Is().
+var (
+    ErrKeyNotFound     = errors.New("key not found")
+    ErrIndexOutOfRange = errors.New("index out of range")
+    ErrBadArgument     = errors.New("bad argument")
+    ErrTypeMismatch    = errors.New("type mismatch")
+)
+
+// Location of failure within document. Embedded into errors returned by
+// traversal functions.
+type location struct {
+    located bool
+    path    []interface{}
+    depth   int
+    kind    string
+}
+
+// Returns full path requested by caller or nil if error is not related to
+// traversal.
+func (l location) Path() []interface{} {
+    return l.path
+}
+
+// Returns number of path keys successfully followed before failure.
+func (l location) Depth() int {
+    return l.depth
+}
+
+// Returns kind of value found at the point of failure: "object", "array",
+// "string", "number", "boolean", "null" or Go type name for other values.
+func (l location) Kind() string {
+    return l.kind
+}
+
+func (l location) suffix() string {
+    if !l.located {
+        return ""
+    }
+    return fmt.Sprintf(" (path %q, depth %d, found %s)", FormatPath(l.path...), l.depth, l.kind)
+}
+
+func kindOf(V interface{}) string {
+    if isNull(V) {
+        return "null"
+    }
+    switch V.(type) {
+    case map[string]interface{}, *Object:
+        return "object"
+    case []interface{}:
+        return "array"
+    case string:
+        return "string"
+    case float64, float32, json.Number,
+        int, int8, int16, int32, int64,
+        uint, uint8, uint16, uint32, uint64, uintptr:
+        return "number"
+    case bool:
+        return "boolean"
+    case nil:
+        return "null"
+    default:
+        return fmt.Sprintf("%T", V)
+    }
+}
+
+// Errors which can be annotated with location.
+type locatable interface {
+    error
+    withLocation(l location) error
+    getLocation() location
+}
+
+func (l location) getLocation() location {
+    return l
+}
+
+// Annotates error created while processing value V with location at zero
+// depth. Other errors are returned as is.
+func here(err error, V interface{}) error {
+    if e, ok := err.(locatable); ok && !e.getLocation().located {
+        return e.withLocation(location{located: true, kind: kindOf(V)})
+    }
+    return err
+}
+
+// Increments depth of location when error is propagated to the parent
+// container.
+func nested(err error) error {
+    if e, ok := err.(locatable); ok {
+        if l := e.getLocation(); l.located && l.path == nil {
+            l.depth++
+            return e.withLocation(l)
+        }
+    }
+    return err
+}
+
+// Sets full path to located error once it reaches function called by user.
+func withPath(err error, keys []interface{}) error {
+    if e, ok := err.(locatable); ok {
+        if l := e.getLocation(); l.located && l.path == nil {
+            l.path = append([]interface{}{}, keys...)
+            return e.withLocation(l)
+        }
+    }
+    return err
+}
+
+// Annotates error with full location.
+func locate(err error, keys []interface{}, depth int, V interface{}) error {
+    if e, ok := err.(locatable); ok {
+        return e.withLocation(location{
+            located: true,
+            path:    append([]interface{}{}, keys...),
+            depth:   depth,
+            kind:    kindOf(V),
+        })
+    }
+    return err
+}
+
 // This error is returned when string key is not found in map.
-type KeyError string
+type KeyError struct {
+    location
+    key string
+}
 
 func newKeyError(key string) KeyError {
-    return KeyError(key)
+    return KeyError{key: key}
 }
 
 func (e KeyError) Error() string {
-    return fmt.Sprintf("Key \"%s\" not found", string(e))
+    return fmt.Sprintf("Key \"%s\" not found", e.key) + e.location.suffix()
 }
 
 // Returns absent key name
 func (e KeyError) Key() string {
-    return string(e)
+    return e.key
+}
+
+// Reports if error matches ErrKeyNotFound.
+func (e KeyError) Is(target error) bool {
+    return target == ErrKeyNotFound
+}
+
+func (e KeyError) withLocation(l location) error {
+    e.location = l
+    return e
 }
 
 // This error is returned when array index is out of range.
-type IndexError int
+type IndexError struct {
+    location
+    index int
+}
 
 func newIndexError(index int) IndexError {
-    return IndexError(index)
+    return IndexError{index: index}
 }
 
 func (e IndexError) Error() string {
-    return fmt.Sprintf("Index \"%d\" is out of range", int(e))
+    return fmt.Sprintf("Index \"%d\" is out of range", e.index) + e.location.suffix()
 }
 
 // Returns absent index value.
 func (e IndexError) Index() int {
-    return int(e)
+    return e.index
+}
+
+// Reports if error matches ErrIndexOutOfRange.
+func (e IndexError) Is(target error) bool {
+    return target == ErrIndexOutOfRange
+}
+
+func (e IndexError) withLocation(l location) error {
+    e.location = l
+    return e
 }
 
 // This error is returned in case when function parameters are incorrect.
-type ArgError string
+type ArgError struct {
+    location
+    msg string
+}
 
 func newArgError(msg string) ArgError {
-    return ArgError(msg)
+    return ArgError{msg: msg}
 }
 
 func (e ArgError) Error() string {
-    return string(e)
+    return e.msg + e.location.suffix()
+}
+
+// Reports if error matches ErrBadArgument.
+func (e ArgError) Is(target error) bool {
+    return target == ErrBadArgument
+}
+
+func (e ArgError) withLocation(l location) error {
+    e.location = l
+    return e
 }
 
 // This error is returned on mismatch of data types.
-type TypeError string
+type TypeError struct {
+    location
+    msg string
+}
 
 func newTypeError(msg string) TypeError {
-    return TypeError(msg)
+    return TypeError{msg: msg}
 }
 
 func (e TypeError) Error() string {
-    return string(e)
+    return e.msg + e.location.suffix()
+}
+
+// Reports if error matches ErrTypeMismatch.
+func (e TypeError) Is(target error) bool {
+    return target == ErrTypeMismatch
+}
+
+func (e TypeError) withLocation(l location) error {
+    e.location = l
+    return e
 }
 
 func s(keys ...interface{}) (interface{}, error) {
     if len(keys) == 0 {
-        return nil, newArgError("No values passed")
+        return nil, here(newArgError("No values passed"), nil)
     } else if len(keys) == 1 {
         return keys[0], nil
     }
@@ -81,76 +256,108 @@ func s(keys ...interface{}) (interface{}, error) {
         m := make(map[string]interface{})
         elem, err := s(keys[1:]...)
         if err != nil {
-            return nil, err
+            return nil, nested(err)
         }
         m[k] = elem
         return m, nil
     case int:
         if k < 0 {
-            return nil, newArgError("Negative index is not allowed")
+            return nil, here(newArgError("Negative index is not allowed"), nil)
         }
         a := make([]interface{}, k+1)
         elem, err := s(keys[1:]...)
         if err != nil {
-            return nil, err
+            return nil, nested(err)
         }
         a[k] = elem
         return a, nil
     default:
-        return nil, newTypeError("Unknown key type")
+        return nil, here(newTypeError("Unknown key type"), nil)
     }
 }
 
 // Query some JSON paths.
 // Invocation: Q(object {}interface, path... interface{}, newvalue interface{}).
 // Returns value and error.
+// Besides decoded JSON, native Go maps with string keys, slices, arrays and
+// structs are traversed via reflection. Struct fields are addressed by names
+// from `json` tags.
 func Q(V interface{}, keys ...interface{}) (interface{}, error) {
-    if len(keys) == 0 {
-        return V, nil
+    for i, key := range keys {
+        next, err := q(V, key)
+        if err != nil {
+            return nil, locate(err, keys, i, V)
+        }
+        V = next
     }
-    key := keys[0]
+    return V, nil
+}
 
-    var next interface{}
+// Follows single key of path.
+func q(V interface{}, key interface{}) (interface{}, error) {
     switch k := key.(type) {
     case string:
+        if o, ok := asObject(V); ok {
+            next, ok := o.Get(k)
+            if !ok {
+                return nil, newKeyError(k)
+            }
+            return next, nil
+        }
         v, ok := V.(map[string]interface{})
         if !ok {
+            if isContainer(V) {
+                return reflectQ(V, key)
+            }
             return nil, newTypeError("Bad container type: not a map")
         }
-        next, ok = v[k]
+        next, ok := v[k]
         if !ok {
             return nil, newKeyError(k)
         }
+        return next, nil
     case int:
         v, ok := V.([]interface{})
         if !ok {
+            if isContainer(V) {
+                return reflectQ(V, key)
+            }
             return nil, newTypeError("Bad container type: not an array")
         }
         if len(v) <= k || k < 0 {
             return nil, newIndexError(k)
         }
-        next = v[k]
+        return v[k], nil
+    case wildcardKey:
+        return nil, newArgError("Wildcard keys are supported only by QAll()")
     default:
         return nil, newTypeError("Unknown key type")
     }
-    return Q(next, keys[1:]...)
 }
 
-func u(V interface{}, keys ...interface{}) (interface{}, error) {
-    if V == nil {
+// Updates value at path within V. If ordered is set, missing objects are
+// created as *Object.
+func u(V interface{}, ordered bool, keys ...interface{}) (interface{}, error) {
+    if isNull(V) {
         // Should never happen if this function is called only by U()
-        return nil, newTypeError("Can't update nil value")
+        return nil, here(newTypeError("Can't update nil value"), V)
     }
     l := len(keys)
     if l < 2 {
-        return nil, newArgError("Incorrect arg length")
+        return nil, here(newArgError("Incorrect arg length"), V)
     }
     key := keys[0]
     switch k := key.(type) {
     case string:
+        if o, ok := asObject(V); ok {
+            return uObject(o, k, keys[1:])
+        }
         m, ok := V.(map[string]interface{})
         if !ok {
-            return nil, newTypeError("Container type mismatch")
+            if isContainer(V) {
+                return reflectU(V, keys...)
+            }
+            return nil, here(newTypeError("Container type mismatch"), V)
         }
         if l == 2 {
             // Reached path destination
@@ -159,38 +366,40 @@ func u(V interface{}, keys ...interface{}) (interface{}, error) {
             return old, nil
         } else {
             // Follow next container
-            if m[k] == nil {
+            if isNull(m[k]) {
                 // Recreate subtree
-                tree, err := s(keys[1:]...)
+                tree, err := build(ordered, keys[1:]...)
                 if err != nil {
-                    return nil, err
+                    return nil, nested(err)
                 }
                 m[k] = tree
                 return nil, nil
             } else {
-                res, err := u(m[k], keys[1:]...)
+                res, err := u(m[k], ordered, keys[1:]...)
                 if size, ok := err.(sliceResizeNeeded) ; ok {
                     // Handle slice resize
-                    newslice := make([]interface{}, size)
-                    copy(newslice, m[k].([]interface{}))
-                    m[k] = newslice
+                    m[k] = resizeSlice(m[k], uint64(size))
                     // Retry with resized array
-                    return u(m[k], keys[1:]...)
+                    res, err = u(m[k], ordered, keys[1:]...)
                 }
-                return res, err
+                return res, nested(err)
             }
         }
     case int:
         a, ok := V.([]interface{})
         if !ok {
-            return nil, newTypeError("Container type mismatch")
+            if isContainer(V) {
+                return reflectU(V, keys...)
+            }
+            return nil, here(newTypeError("Container type mismatch"), V)
         }
         if k < 0 {
-            return nil, newIndexError(k)
+            return nil, here(newIndexError(k), V)
         }
         if k >= len(a) {
             return nil, newSliceResizeNeeded(uint64(k + 1))
         }
+        ordered = ordered || orderedArray(a)
         if l == 2 {
             // Reached path destination
             old := a[k]
@@ -198,36 +407,49 @@ func u(V interface{}, keys ...interface{}) (interface{}, error) {
             return old, nil
         } else {
             // Follow next container
-            if a[k] == nil {
+            if isNull(a[k]) {
                 // Recreate subtree
-                tree, err := s(keys[1:]...)
+                tree, err := build(ordered, keys[1:]...)
                 if err != nil {
-                    return nil, err
+                    return nil, nested(err)
                 }
                 a[k] = tree
                 return nil, nil
             } else {
-                res, err := u(a[k], keys[1:]...)
+                res, err := u(a[k], ordered, keys[1:]...)
                 if size, ok := err.(sliceResizeNeeded) ; ok {
                     // Handle slice resize
-                    newslice := make([]interface{}, size)
-                    copy(newslice, a[k].([]interface{}))
-                    a[k] = newslice
+                    a[k] = resizeSlice(a[k], uint64(size))
                     // Retry with resized array
-                    return u(a[k], keys[1:]...)
+                    res, err = u(a[k], ordered, keys[1:]...)
                 }
-                return res, err
+                return res, nested(err)
             }
         }
     default:
-        return nil, newTypeError("Unknown key type")
+        return nil, here(newTypeError("Unknown key type"), V)
     }
 }
 
 // Apply some changes to JSON.
 // Invocation: U(object {}interface, path... interface{}, newvalue interface{}).
 // Returns old value and error.
+// Native Go containers are updated in place and new values are converted to
+// type of destination like QInto() does. Structs and arrays have to be
+// reachable via pointer to be updated. Missing objects created within
+// ordered documents are *Object.
 func U(V *interface{}, keys ...interface{}) (interface{}, error) {
+    return update(V, false, keys...)
+}
+
+// Same as U(), but missing objects are always created as *Object. U()
+// creates them only within ordered documents, which can't be recognized if
+// V holds nil.
+func UOrdered(V *interface{}, keys ...interface{}) (interface{}, error) {
+    return update(V, true, keys...)
+}
+
+func update(V *interface{}, ordered bool, keys ...interface{}) (interface{}, error) {
     if V == nil {
         return nil, newArgError("nil pointer dereference")
     }
@@ -239,24 +461,147 @@ func U(V *interface{}, keys ...interface{}) (interface{}, error) {
         *V = keys[0]
         return oldval, nil
     } else {
-        if *V == nil {
-            tree, err := s(keys...)
+        keys = resolveAppend(*V, keys)
+        if isNull(*V) {
+            tree, err := build(ordered, keys...)
             *V = tree
-            return nil, err
+            return nil, withPath(err, keys[:l-1])
         }
-        res, err := u(*V, keys...)
+        res, err := u(*V, ordered, keys...)
         if size, ok := err.(sliceResizeNeeded) ; ok {
             // Handle slice resize
-            newslice := make([]interface{}, size)
-            copy(newslice, (*V).([]interface{}))
-            *V = newslice
+            *V = resizeSlice(*V, uint64(size))
             // Retry with resized array
-            return u(*V, keys...)
+            res, err = u(*V, ordered, keys...)
         }
-        return res, err
+        return res, withPath(err, keys[:l-1])
     }
 }
 
+// Follows path and replaces value found there with result of fn. Parent
+// containers are rewritten on the way back, so fn may return new slice
+// header when it changes array length.
+func apply(V *interface{}, keys []interface{}, fn func(interface{}) (interface{}, error)) error {
+    if len(keys) == 0 {
+        res, err := fn(*V)
+        if err != nil {
+            return here(err, *V)
+        }
+        *V = res
+        return nil
+    }
+    switch k := keys[0].(type) {
+    case string:
+        if o, ok := asObject(*V); ok {
+            elem, ok := o.Get(k)
+            if !ok {
+                return here(newKeyError(k), *V)
+            }
+            if err := apply(&elem, keys[1:], fn); err != nil {
+                return nested(err)
+            }
+            o.Set(k, elem)
+            return nil
+        }
+        m, ok := (*V).(map[string]interface{})
+        if !ok {
+            if isContainer(*V) {
+                return reflectApply(*V, keys, fn)
+            }
+            return here(newTypeError("Bad container type: not a map"), *V)
+        }
+        elem, ok := m[k]
+        if !ok {
+            return here(newKeyError(k), *V)
+        }
+        err := apply(&elem, keys[1:], fn)
+        if err != nil {
+            return nested(err)
+        }
+        m[k] = elem
+        return nil
+    case int:
+        a, ok := (*V).([]interface{})
+        if !ok {
+            if isContainer(*V) {
+                return reflectApply(*V, keys, fn)
+            }
+            return here(newTypeError("Bad container type: not an array"), *V)
+        }
+        if len(a) <= k || k < 0 {
+            return here(newIndexError(k), *V)
+        }
+        return nested(apply(&a[k], keys[1:], fn))
+    default:
+        return here(newTypeError("Unknown key type"), *V)
+    }
+}
+
+// Delete value from JSON.
+// Invocation: D(object *interface{}, path... interface{}).
+// Removes key from object or element from array, shifting all subsequent
+// elements. Returns removed value and error. Native Go maps and slices are
+// supported as well, while struct fields and elements of fixed-size arrays
+// can't be removed and are reported with TypeError.
+func D(V *interface{}, keys ...interface{}) (interface{}, error) {
+    if V == nil {
+        return nil, newArgError("nil pointer dereference")
+    }
+    l := len(keys)
+    if l < 1 {
+        return nil, newArgError("Incorrect arg length")
+    }
+    var removed interface{}
+    err := apply(V, keys[:l-1], func(C interface{}) (interface{}, error) {
+        switch k := keys[l-1].(type) {
+        case string:
+            if o, ok := asObject(C); ok {
+                if removed, ok = o.Delete(k); !ok {
+                    return nil, newKeyError(k)
+                }
+                return o, nil
+            }
+            m, ok := C.(map[string]interface{})
+            if !ok {
+                if isContainer(C) {
+                    res, r, err := reflectD(C, k)
+                    removed = r
+                    return res, err
+                }
+                return nil, newTypeError("Bad container type: not a map")
+            }
+            if removed, ok = m[k]; !ok {
+                return nil, newKeyError(k)
+            }
+            delete(m, k)
+            return m, nil
+        case int:
+            a, ok := C.([]interface{})
+            if !ok {
+                if isContainer(C) {
+                    res, r, err := reflectD(C, k)
+                    removed = r
+                    return res, err
+                }
+                return nil, newTypeError("Bad container type: not an array")
+            }
+            if len(a) <= k || k < 0 {
+                return nil, newIndexError(k)
+            }
+            removed = a[k]
+            copy(a[k:], a[k+1:])
+            a[len(a)-1] = nil
+            return a[:len(a)-1], nil
+        default:
+            return nil, newTypeError("Unknown key type")
+        }
+    })
+    if err != nil {
+        return nil, withPath(err, keys)
+    }
+    return removed, nil
+}
+
 // Same as Q(), but asserts bool type for retrieved value. If type assertion failed
 // TypeError is returned.
 func QBool(V interface{}, keys ...interface{}) (bool, error) {
@@ -266,21 +611,22 @@ func QBool(V interface{}, keys ...interface{}) (bool, error) {
     }
     res, ok := val.(bool)
     if !ok {
-        return false, newTypeError("Retrieved value is not a boolean")
+        return false, locate(newTypeError("Retrieved value is not a boolean"), keys, len(keys), val)
     }
     return res, nil
 }
 
-// Same as Q(), but asserts float64 type for retrieved value. If type assertion failed
+// Same as Q(), but converts retrieved number to float64. Numbers of any Go
+// numeric type and json.Number are accepted. If value is not a number
 // TypeError is returned.
 func QNumber(V interface{}, keys ...interface{}) (float64, error) {
     val, err := Q(V, keys...)
     if err != nil {
         return 0, err
     }
-    res, ok := val.(float64)
-    if !ok {
-        return 0, newTypeError("Retrieved value is not a number")
+    res, err := toFloat64(val)
+    if err != nil {
+        return 0, locate(err, keys, len(keys), val)
     }
     return res, nil
 }
@@ -294,7 +640,7 @@ func QString(V interface{}, keys ...interface{}) (string, error) {
     }
     res, ok := val.(string)
     if !ok {
-        return "", newTypeError("Retrieved value is not a string")
+        return "", locate(newTypeError("Retrieved value is not a string"), keys, len(keys), val)
     }
     return res, nil
 }
@@ -308,21 +654,43 @@ func QList(V interface{}, keys ...interface{}) ([]interface{}, error) {
     }
     res, ok := val.([]interface{})
     if !ok {
-        return nil, newTypeError("Retrieved value is not a list")
+        return nil, locate(newTypeError("Retrieved value is not a list"), keys, len(keys), val)
     }
     return res, nil
 }
 
 // Same as Q(), but asserts map[string]interface{} type for retrieved value. If type
-// assertion failed TypeError is returned.
+// assertion failed TypeError is returned. Ordered object is returned as
+// a copy of its members.
 func QObject(V interface{}, keys ...interface{}) (map[string]interface{}, error) {
     val, err := Q(V, keys...)
     if err != nil {
         return nil, err
     }
+    if o, ok := asObject(val); ok {
+        res := make(map[string]interface{}, o.Len())
+        for k, v := range o.Map() {
+            res[k] = v
+        }
+        return res, nil
+    }
     res, ok := val.(map[string]interface{})
     if !ok {
-        return nil, newTypeError("Retrieved value is not an object")
+        return nil, locate(newTypeError("Retrieved value is not an object"), keys, len(keys), val)
+    }
+    return res, nil
+}
+
+// Same as Q(), but asserts *Object type for retrieved value. If type
+// assertion failed TypeError is returned.
+func QOrderedObject(V interface{}, keys ...interface{}) (*Object, error) {
+    val, err := Q(V, keys...)
+    if err != nil {
+        return nil, err
+    }
+    res, ok := asObject(val)
+    if !ok {
+        return nil, locate(newTypeError("Retrieved value is not an ordered object"), keys, len(keys), val)
     }
     return res, nil
 }
@@ -333,8 +701,8 @@ func QNull(V interface{}, keys ...interface{}) error {
     if err != nil {
         return err
     }
-    if val != nil {
-        return newTypeError("Retrieved value is not null")
+    if !isNull(val) {
+        return locate(newTypeError("Retrieved value is not null"), keys, len(keys), val)
     }
     return nil
 }
@@ -3,6 +3,8 @@ package qjson
 import (
     "testing"
     "encoding/json"
+    "errors"
+    "reflect"
     "strings"
     "time"
 )
@@ -448,7 +450,7 @@ func TestUpdateBadKey2(t *testing.T) {
 
 func TestQueryBadKey(t *testing.T) {
     j := loadJSON(`{"a":[]}`, t)
-    _, err := Q(&j, .0)
+    _, err := Q(j, .0)
     _, ok := err.(TypeError)
     if !ok {
         t.Fail()
@@ -511,7 +513,7 @@ func TestBadSubtreeConstruct(t *testing.T) {
 }
 
 func TestArtificalBadInnerUpdate(t *testing.T) {
-    _, err := u(nil, 1, 2, 3)
+    _, err := u(nil, false, 1, 2, 3)
     if _, ok := err.(TypeError) ; !ok {
         t.Fail()
     }
@@ -519,7 +521,7 @@ func TestArtificalBadInnerUpdate(t *testing.T) {
 
 func TestArtificalInnerUpdateWithNoValue(t *testing.T) {
     j := loadJSON(`{}`, t)
-    _, err := u(j, "test")
+    _, err := u(j, false, "test")
     if _, ok := err.(ArgError) ; !ok {
         t.Fail()
     }
@@ -699,3 +701,187 @@ func TestQNull(t *testing.T) {
         t.Fail()
     }
 }
+
+func TestDeleteKey(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    old, err := D(&j, "menu", "popup")
+    if err != nil {
+        t.Fail()
+    }
+    if _, ok := old.(map[string]interface{}) ; !ok {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"menu":{"id":"file","value":"File"}}` {
+        t.Fail()
+    }
+    _, err = D(&j, "menu", "popup")
+    if e, ok := err.(KeyError) ; !ok || e.Key() != "popup" {
+        t.Fail()
+    }
+}
+
+func TestDeleteElement(t *testing.T) {
+    j := loadJSON(`{"a": [[1, 2, 3], 4]}`, t)
+    old, err := D(&j, "a", 0, 1)
+    if err != nil || old != 2. {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":[[1,3],4]}` {
+        t.Fail()
+    }
+    old, err = D(&j, "a", 0)
+    if err != nil || dumpJSON(old, t) != `[1,3]` {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":[4]}` {
+        t.Fail()
+    }
+    j = loadJSON(`[1, 2]`, t)
+    D(&j, 1)
+    D(&j, 0)
+    if dumpJSON(j, t) != `[]` {
+        t.Fail()
+    }
+}
+
+func TestDeleteErrors(t *testing.T) {
+    j := loadJSON(`{"a": [1, 2, 3]}`, t)
+    _, err := D(&j, "a", 3)
+    if e, ok := err.(IndexError) ; !ok || e.Index() != 3 {
+        t.Fail()
+    }
+    _, err = D(&j, "a", -1)
+    if _, ok := err.(IndexError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j, "b", 0)
+    if _, ok := err.(KeyError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j, "a", "b")
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j, 0)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j, 0, 0)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j, "a", .0)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j, .0, 0)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(&j)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+    _, err = D(nil, "a")
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+    if dumpJSON(j, t) != `{"a":[1,2,3]}` {
+        t.Fail()
+    }
+}
+
+func TestErrorLocation(t *testing.T) {
+    j := loadJSON(`{"a": {"b": [1, 2, {"c": true}]}}`, t)
+
+    _, err := Q(j, "a", "b", 2, "d")
+    var kerr KeyError
+    if !errors.As(err, &kerr) || !errors.Is(err, ErrKeyNotFound) {
+        t.FailNow()
+    }
+    if !reflect.DeepEqual(kerr.Path(), []interface{}{"a", "b", 2, "d"}) ||
+        kerr.Depth() != 3 || kerr.Kind() != "object" || kerr.Key() != "d" {
+        t.Fail()
+    }
+    if kerr.Error() != `Key "d" not found (path "a.b[2].d", depth 3, found object)` {
+        t.Error(kerr.Error())
+    }
+
+    _, err = Q(j, "a", "b", 5, "c")
+    var ierr IndexError
+    if !errors.As(err, &ierr) || !errors.Is(err, ErrIndexOutOfRange) {
+        t.FailNow()
+    }
+    if ierr.Depth() != 2 || ierr.Kind() != "array" || ierr.Index() != 5 {
+        t.Fail()
+    }
+
+    _, err = Q(j, "a", "b", 0, "c")
+    var terr TypeError
+    if !errors.As(err, &terr) || !errors.Is(err, ErrTypeMismatch) {
+        t.FailNow()
+    }
+    if terr.Depth() != 3 || terr.Kind() != "number" {
+        t.Fail()
+    }
+
+    _, err = QString(j, "a", "b", 2, "c")
+    if !errors.As(err, &terr) || terr.Depth() != 4 || terr.Kind() != "boolean" {
+        t.Fail()
+    }
+
+    _, err = Q(j, "a", Any)
+    if !errors.Is(err, ErrBadArgument) || errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+}
+
+func TestUpdateErrorLocation(t *testing.T) {
+    j := loadJSON(`{"a": {"b": [1, 2, {"c": true}]}}`, t)
+    _, err := U(&j, "a", "b", "c", 1)
+    var terr TypeError
+    if !errors.As(err, &terr) {
+        t.FailNow()
+    }
+    if !reflect.DeepEqual(terr.Path(), []interface{}{"a", "b", "c"}) ||
+        terr.Depth() != 2 || terr.Kind() != "array" {
+        t.Fail()
+    }
+
+    _, err = U(&j, "a", "x", "y", -1, 1)
+    var aerr ArgError
+    if !errors.As(err, &aerr) || !errors.Is(err, ErrBadArgument) {
+        t.FailNow()
+    }
+    if aerr.Depth() != 3 || aerr.Kind() != "null" {
+        t.Fail()
+    }
+
+    _, err = U(&j, "a", "b", 2, "c", "d", 1)
+    if !errors.As(err, &terr) || terr.Depth() != 4 || terr.Kind() != "boolean" {
+        t.Fail()
+    }
+
+    _, err = D(&j, "a", "b", 2, "x")
+    var kerr KeyError
+    if !errors.As(err, &kerr) || kerr.Depth() != 3 || kerr.Kind() != "object" ||
+        !reflect.DeepEqual(kerr.Path(), []interface{}{"a", "b", 2, "x"}) {
+        t.Fail()
+    }
+
+    _, err = D(&j, "a", "x", 2)
+    if !errors.As(err, &kerr) || kerr.Depth() != 1 || kerr.Key() != "x" {
+        t.Fail()
+    }
+}
+
+func TestErrorWithoutLocation(t *testing.T) {
+    _, err := U(nil, "a", 1)
+    var aerr ArgError
+    if !errors.As(err, &aerr) || aerr.Path() != nil || aerr.Kind() != "" {
+        t.Fail()
+    }
+    if err.Error() != "nil pointer dereference" {
+        t.Fail()
+    }
+}
@@ -0,0 +1,363 @@
+package qjson
+
+import (
+    "reflect"
+    "sort"
+)
+
+// Reports whether V is native Go container which can be traversed by
+// reflection.
+func isContainer(V interface{}) bool {
+    switch indirect(reflect.ValueOf(V)).Kind() {
+    case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+        return true
+    default:
+        return false
+    }
+}
+
+// Dereferences pointers and interfaces. Returns invalid value if nil was
+// found on the way.
+func indirect(v reflect.Value) reflect.Value {
+    for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+        if v.IsNil() {
+            return reflect.Value{}
+        }
+        v = v.Elem()
+    }
+    return v
+}
+
+// Container slot addressed by single key within native Go value.
+type slot struct {
+    container reflect.Value
+    key       reflect.Value // map key, invalid for fields and elements
+    elem      reflect.Value // field or element, invalid for map entries
+}
+
+func (s slot) typ() reflect.Type {
+    if s.key.IsValid() {
+        return s.container.Type().Elem()
+    }
+    return s.elem.Type()
+}
+
+// Returns current value in slot or invalid value if map has no such key.
+func (s slot) get() reflect.Value {
+    if s.key.IsValid() {
+        return s.container.MapIndex(s.key)
+    }
+    return s.elem
+}
+
+func (s slot) set(v reflect.Value) error {
+    if s.key.IsValid() {
+        s.container.SetMapIndex(s.key, v)
+        return nil
+    }
+    if !s.elem.CanSet() {
+        return newTypeError("Can't update value held by unaddressable container, pass a pointer")
+    }
+    s.elem.Set(v)
+    return nil
+}
+
+// Finds slot addressed by key within maps with string keys, slices, arrays
+// and structs. Struct fields are addressed by their exact JSON names, the
+// same way keys of generic maps are. Errors are
+// the same Q() returns for generic containers.
+func lookupSlot(V interface{}, key interface{}, update bool) (slot, error) {
+    v := indirect(reflect.ValueOf(V))
+    switch k := key.(type) {
+    case string:
+        switch {
+        case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+            if update && v.IsNil() {
+                // Nil map can't receive new entries
+                if !v.CanSet() {
+                    return slot{}, newTypeError("Can't update nil map held by unaddressable container, pass a pointer")
+                }
+                v.Set(reflect.MakeMap(v.Type()))
+            }
+            kv := reflect.ValueOf(k).Convert(v.Type().Key())
+            if !update && !v.MapIndex(kv).IsValid() {
+                return slot{}, newKeyError(k)
+            }
+            return slot{container: v, key: kv}, nil
+        case v.Kind() == reflect.Struct:
+            f := lookupField(structFields(v.Type()), k, false)
+            if f == nil {
+                return slot{}, newKeyError(k)
+            }
+            fv := fieldByIndex(v, f.index, update)
+            if !fv.IsValid() {
+                return slot{}, newKeyError(k)
+            }
+            return slot{container: v, elem: fv}, nil
+        default:
+            return slot{}, newTypeError("Bad container type: not a map")
+        }
+    case int:
+        if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+            return slot{}, newTypeError("Bad container type: not an array")
+        }
+        if k < 0 {
+            return slot{}, newIndexError(k)
+        }
+        if k >= v.Len() {
+            if update && v.Kind() == reflect.Slice {
+                return slot{}, newSliceResizeNeeded(uint64(k + 1))
+            }
+            return slot{}, newIndexError(k)
+        }
+        return slot{container: v, elem: v.Index(k)}, nil
+    default:
+        return slot{}, newTypeError("Unknown key type")
+    }
+}
+
+// Same as q(), but for native Go containers.
+func reflectQ(V interface{}, key interface{}) (interface{}, error) {
+    s, err := lookupSlot(V, key, false)
+    if err != nil {
+        return nil, err
+    }
+    elem := s.get()
+    if !elem.CanInterface() {
+        return nil, newTypeError("Value is not accessible")
+    }
+    return elem.Interface(), nil
+}
+
+// Converts value to type t. Assignable values are used as is, others are
+// decoded like QInto() does.
+func convertTo(value interface{}, t reflect.Type) (reflect.Value, error) {
+    if value != nil && reflect.TypeOf(value).AssignableTo(t) {
+        return reflect.ValueOf(value), nil
+    }
+    res := reflect.New(t).Elem()
+    if err := decode(value, res, nil, false); err != nil {
+        // Location is set by caller
+        if e, ok := err.(locatable); ok {
+            err = e.withLocation(location{})
+        }
+        return reflect.Value{}, err
+    }
+    return res, nil
+}
+
+// Returns copy of slice extended to given size.
+func resizeSlice(V interface{}, size uint64) interface{} {
+    v := reflect.ValueOf(V)
+    res := reflect.MakeSlice(v.Type(), int(size), int(size))
+    reflect.Copy(res, v)
+    return res.Interface()
+}
+
+// Same as u(), but for native Go containers. New values are converted to
+// type of their destination.
+func reflectU(V interface{}, keys ...interface{}) (interface{}, error) {
+    l := len(keys)
+    sl, err := lookupSlot(V, keys[0], true)
+    if size, ok := err.(sliceResizeNeeded); ok {
+        // Grow slice in place if it's reachable via pointer
+        v := indirect(reflect.ValueOf(V))
+        if !v.CanSet() {
+            return nil, err
+        }
+        v.Set(reflect.ValueOf(resizeSlice(v.Interface(), uint64(size))))
+        sl, err = lookupSlot(V, keys[0], true)
+    }
+    if err != nil {
+        return nil, here(err, V)
+    }
+    var old interface{}
+    cur := sl.get()
+    if cur.IsValid() && cur.CanInterface() {
+        old = cur.Interface()
+    }
+    if l == 2 {
+        // Reached path destination
+        nv, err := convertTo(keys[1], sl.typ())
+        if err == nil {
+            err = sl.set(nv)
+        }
+        if err != nil {
+            return nil, here(err, V)
+        }
+        return old, nil
+    }
+    // Follow next container
+    if !indirect(cur).IsValid() {
+        // Recreate subtree
+        tree, err := s(keys[1:]...)
+        if err != nil {
+            return nil, nested(err)
+        }
+        nv, err := convertTo(tree, sl.typ())
+        if err == nil {
+            err = sl.set(nv)
+        }
+        if err != nil {
+            return nil, here(err, V)
+        }
+        return nil, nil
+    }
+    if cur.Kind() == reflect.Map && cur.IsNil() {
+        // Allocate nil map before adding entries to it
+        m := reflect.MakeMap(cur.Type())
+        if err := sl.set(m); err != nil {
+            return nil, here(err, V)
+        }
+        cur, old = m, m.Interface()
+    }
+    var child interface{}
+    switch cur.Kind() {
+    case reflect.Struct, reflect.Array:
+        // Value types are updated via temporary copy, since map entries
+        // are not addressable
+        tmp := reflect.New(cur.Type())
+        tmp.Elem().Set(cur)
+        child = tmp.Interface()
+    default:
+        child = old
+    }
+    res, err := u(child, false, keys[1:]...)
+    if size, ok := err.(sliceResizeNeeded); ok {
+        // Handle slice resize
+        child = resizeSlice(indirect(cur).Interface(), uint64(size))
+        if err = sl.set(reflect.ValueOf(child)); err != nil {
+            return nil, here(err, V)
+        }
+        // Retry with resized array
+        res, err = u(child, false, keys[1:]...)
+    }
+    if err != nil {
+        return nil, nested(err)
+    }
+    if cur.Kind() == reflect.Struct || cur.Kind() == reflect.Array {
+        if err := sl.set(reflect.ValueOf(child).Elem()); err != nil {
+            return nil, here(err, V)
+        }
+    }
+    return res, nil
+}
+
+// Same as apply(), but for native Go containers. Values returned by fn are
+// converted to type of their slot.
+func reflectApply(V interface{}, keys []interface{}, fn func(interface{}) (interface{}, error)) error {
+    sl, err := lookupSlot(V, keys[0], false)
+    if err != nil {
+        return here(err, V)
+    }
+    cur := sl.get()
+    if !cur.CanInterface() {
+        return here(newTypeError("Value is not accessible"), V)
+    }
+    child := cur.Interface()
+    copied := cur.Kind() == reflect.Struct || cur.Kind() == reflect.Array
+    if copied {
+        // Value types are modified via temporary copy, since map entries
+        // are not addressable
+        tmp := reflect.New(cur.Type())
+        tmp.Elem().Set(cur)
+        child = tmp.Interface()
+    }
+    if err := apply(&child, keys[1:], fn); err != nil {
+        return nested(err)
+    }
+    var nv reflect.Value
+    if p := reflect.ValueOf(child); copied && p.Type() == reflect.PtrTo(cur.Type()) && !p.IsNil() {
+        nv = p.Elem()
+    } else if nv, err = convertTo(child, sl.typ()); err != nil {
+        return here(err, V)
+    }
+    if err := sl.set(nv); err != nil {
+        return here(err, V)
+    }
+    return nil
+}
+
+// Removes key from native map or element from native slice. Returns
+// resulting container and removed value. Struct fields and array elements
+// can't be removed.
+func reflectD(C interface{}, key interface{}) (interface{}, interface{}, error) {
+    v := indirect(reflect.ValueOf(C))
+    switch key.(type) {
+    case string:
+        if v.Kind() == reflect.Struct {
+            return nil, nil, newTypeError("Can't delete struct field")
+        }
+    case int:
+        if v.Kind() == reflect.Array {
+            return nil, nil, newTypeError("Can't delete element of fixed-size array")
+        }
+    }
+    removed, err := reflectQ(C, key)
+    if err != nil {
+        return nil, nil, err
+    }
+    if k, ok := key.(string); ok {
+        v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), reflect.Value{})
+        return C, removed, nil
+    }
+    k := key.(int)
+    res := reflect.AppendSlice(v.Slice(0, k), v.Slice(k+1, v.Len()))
+    // Release reference held by the last element
+    v.Index(v.Len() - 1).Set(reflect.Zero(v.Type().Elem()))
+    return res.Interface(), removed, nil
+}
+
+// Returns length of native slice or array.
+func arrayLen(V interface{}) (int, bool) {
+    v := indirect(reflect.ValueOf(V))
+    if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+        return 0, false
+    }
+    return v.Len(), true
+}
+
+// Returns elements of native slice or array.
+func nativeElems(V interface{}) ([]interface{}, bool) {
+    v := indirect(reflect.ValueOf(V))
+    if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+        return nil, false
+    }
+    res := make([]interface{}, 0, v.Len())
+    for i := 0; i < v.Len(); i++ {
+        if elem := v.Index(i); elem.CanInterface() {
+            res = append(res, elem.Interface())
+        }
+    }
+    return res, true
+}
+
+// Returns JSON names and values of members of native map with string keys
+// or struct. Map keys are sorted, struct fields keep declaration order.
+func nativeMembers(V interface{}) ([]string, map[string]interface{}, bool) {
+    v := indirect(reflect.ValueOf(V))
+    var keys []string
+    values := make(map[string]interface{})
+    switch {
+    case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+        iter := v.MapRange()
+        for iter.Next() {
+            k := iter.Key().String()
+            keys = append(keys, k)
+            values[k] = iter.Value().Interface()
+        }
+        sort.Strings(keys)
+    case v.Kind() == reflect.Struct:
+        for _, f := range structFields(v.Type()) {
+            fv := fieldByIndex(v, f.index, false)
+            if !fv.IsValid() || !fv.CanInterface() {
+                continue
+            }
+            keys = append(keys, f.name)
+            values[f.name] = fv.Interface()
+        }
+    default:
+        return nil, nil, false
+    }
+    return keys, values, true
+}
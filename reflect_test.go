@@ -0,0 +1,267 @@
+package qjson
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type reflectServer struct {
+    Host    string            `json:"host"`
+    Port    int               `json:"port"`
+    Aliases []string          `json:"aliases"`
+    Labels  map[string]string `json:"labels"`
+    Backup  *reflectServer    `json:"backup,omitempty"`
+    Ignored string            `json:"-"`
+}
+
+type reflectConfig struct {
+    Servers []reflectServer          `json:"servers"`
+    ByName  map[string]reflectServer `json:"by_name"`
+    Extra   map[string]interface{}   `json:"extra"`
+    Matrix  [2][2]int                `json:"matrix"`
+}
+
+func newReflectConfig() *reflectConfig {
+    return &reflectConfig{
+        Servers: []reflectServer{
+            {Host: "a", Port: 1, Aliases: []string{"x", "y"}},
+            {Host: "b", Port: 2, Labels: map[string]string{"env": "prod"}},
+        },
+        ByName: map[string]reflectServer{
+            "c": {Host: "c", Port: 3},
+        },
+        Extra:  map[string]interface{}{"list": []map[string]interface{}{{"k": 1.0}}},
+        Matrix: [2][2]int{{1, 2}, {3, 4}},
+    }
+}
+
+func TestQueryNative(t *testing.T) {
+    c := newReflectConfig()
+    if v, err := QString(c, "servers", 0, "aliases", 1); err != nil || v != "y" {
+        t.Fail()
+    }
+    if v, err := QString(c, "servers", 1, "labels", "env"); err != nil || v != "prod" {
+        t.Fail()
+    }
+    if v, err := QNumber(c, "by_name", "c", "port"); err != nil || v != 3 {
+        t.Fail()
+    }
+    if v, err := QNumber(c, "extra", "list", 0, "k"); err != nil || v != 1 {
+        t.Fail()
+    }
+    if v, err := QInt(*c, "matrix", 1, 0); err != nil || v != 3 {
+        t.Fail()
+    }
+    // Native values are mixed freely with decoded JSON
+    j := loadJSON(`{"cfg": null}`, t)
+    U(&j, "cfg", c)
+    if v, err := QString(j, "cfg", "servers", 0, "host"); err != nil || v != "a" {
+        t.Fail()
+    }
+}
+
+func TestQueryNativeErrors(t *testing.T) {
+    c := newReflectConfig()
+    var kerr KeyError
+    _, err := Q(c, "servers", 0, "Ignored")
+    if !errors.As(err, &kerr) || kerr.Depth() != 2 || kerr.Key() != "Ignored" {
+        t.Fail()
+    }
+    _, err = Q(c, "servers", 0, "backup", "host")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    var ierr IndexError
+    _, err = Q(c, "servers", 5)
+    if !errors.As(err, &ierr) || FormatPath(ierr.Path()...) != "servers[5]" {
+        t.Fail()
+    }
+    _, err = Q(c, "by_name", 0)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    _, err = Q(c, "by_name", "missing")
+    if !errors.Is(err, ErrKeyNotFound) {
+        t.Fail()
+    }
+    // Field names are case-sensitive like keys of generic maps
+    _, err = Q(c, "servers", 0, "HOST")
+    if !errors.As(err, &kerr) || kerr.Key() != "HOST" {
+        t.Fail()
+    }
+    var j interface{} = c
+    if _, err = U(&j, "servers", 0, "Port", 1); !errors.Is(err, ErrKeyNotFound) || c.Servers[0].Port != 1 {
+        t.Fail()
+    }
+    _, err = Q(map[int]string{1: "a"}, "1")
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+}
+
+func TestUpdateNative(t *testing.T) {
+    c := newReflectConfig()
+    var j interface{} = c
+    old, err := U(&j, "servers", 0, "port", 8080.0)
+    if err != nil || old != 1 || c.Servers[0].Port != 8080 {
+        t.Fail()
+    }
+    // Map entries holding structs are updated via copy
+    if _, err = U(&j, "by_name", "c", "host", "cc"); err != nil || c.ByName["c"].Host != "cc" {
+        t.Fail()
+    }
+    // Slices are grown as needed
+    if _, err = U(&j, "servers", 0, "aliases", 3, "w"); err != nil ||
+        !reflect.DeepEqual(c.Servers[0].Aliases, []string{"x", "y", "", "w"}) {
+        t.Fail()
+    }
+    if _, err = U(&j, "servers", Append, "host", "d"); err != nil ||
+        len(c.Servers) != 3 || c.Servers[2].Host != "d" {
+        t.Fail()
+    }
+    // Missing subtrees are created and converted to field type
+    if _, err = U(&j, "servers", 1, "backup", "aliases", 0, "z"); err != nil ||
+        c.Servers[1].Backup == nil || c.Servers[1].Backup.Aliases[0] != "z" {
+        t.Fail()
+    }
+    if _, err = U(&j, "extra", "list", 0, "k", 2.0); err != nil || c.Extra["list"].([]map[string]interface{})[0]["k"] != 2.0 {
+        t.Fail()
+    }
+
+    var terr TypeError
+    _, err = U(&j, "servers", 0, "port", "http")
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "servers[0].port" || terr.Depth() != 2 {
+        t.Fail()
+    }
+    if c.Servers[0].Port != 8080 {
+        t.Fail()
+    }
+    var v interface{} = *c
+    _, err = U(&v, "servers", 0, "host", "x")
+    if err != nil || c.Servers[0].Host != "x" {
+        // Slice shares backing array with original value
+        t.Fail()
+    }
+    _, err = U(&v, "matrix", 0, 0, 5)
+    if !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+
+    // Nil maps are allocated on update
+    type withMap struct {
+        M map[string]int `json:"m"`
+    }
+    wm := &withMap{}
+    var w interface{} = wm
+    if _, err = U(&w, "m", "x", 1); err != nil || wm.M["x"] != 1 {
+        t.Fail()
+    }
+    var nm map[string]int
+    w = &nm
+    if _, err = U(&w, "y", 2); err != nil || nm["y"] != 2 {
+        t.Fail()
+    }
+    w = withMap{}
+    if _, err = U(&w, "m", "x", 1); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+}
+
+func TestPointerNative(t *testing.T) {
+    c := newReflectConfig()
+    if v, err := QPointer(c, "/servers/0/aliases/1"); err != nil || v != "y" {
+        t.Errorf("unexpected result: %v, %v", v, err)
+    }
+    if v, err := QPointer(c, "/matrix/1/0"); err != nil || v != 3 {
+        t.Errorf("unexpected result: %v, %v", v, err)
+    }
+    if v, err := QPointer(c, "/by_name/c/port"); err != nil || v != 3 {
+        t.Errorf("unexpected result: %v, %v", v, err)
+    }
+    keys, err := PointerKeys(c, "/servers/-")
+    if err != nil || len(keys) != 2 || keys[1] != 2 {
+        t.Errorf("unexpected keys: %v, %v", keys, err)
+    }
+    if _, err := QPointer(c, "/servers/x"); err == nil {
+        t.Fail()
+    }
+    var V interface{} = c
+    if _, err := UPointer(&V, "/servers/0/aliases/-", "z"); err != nil || len(c.Servers[0].Aliases) != 3 {
+        t.Error(err)
+    }
+}
+
+func TestQAllNative(t *testing.T) {
+    c := newReflectConfig()
+    matches, err := QAll(c, "servers", Any, "host")
+    if err != nil || !reflect.DeepEqual(matchPaths(matches), []string{"servers[0].host", "servers[1].host"}) ||
+        matches[1].Value != "b" {
+        t.Errorf("unexpected result: %v, %v", matches, err)
+    }
+    matches, err = QAll(c, "matrix", Any, 1)
+    if err != nil || len(matches) != 2 || matches[0].Value != 2 || matches[1].Value != 4 {
+        t.Errorf("unexpected result: %v, %v", matches, err)
+    }
+    // Struct fields keep declaration order
+    matches, err = QAll(c, "servers", 0, Any)
+    if err != nil || !reflect.DeepEqual(matchPaths(matches), []string{
+        "servers[0].host", "servers[0].port", "servers[0].aliases", "servers[0].labels", "servers[0].backup",
+    }) {
+        t.Errorf("unexpected result: %v, %v", matchPaths(matches), err)
+    }
+    matches, err = QAll(c, Descend, "port")
+    if err != nil || len(matches) != 3 {
+        t.Errorf("unexpected result: %v, %v", matches, err)
+    }
+    values := jsonPathValues(t, c, `$.servers[?length(@.aliases) == 2].host`)
+    if len(values) != 1 || values[0] != "a" {
+        t.Errorf("unexpected result: %v", values)
+    }
+    values = jsonPathValues(t, c, `$.servers[-1:].labels.env`)
+    if len(values) != 1 || values[0] != "prod" {
+        t.Errorf("unexpected result: %v", values)
+    }
+}
+
+func TestDeleteNative(t *testing.T) {
+    c := newReflectConfig()
+    var V interface{} = c
+    removed, err := D(&V, "servers", 0, "aliases", 0)
+    if err != nil || removed != "x" || !reflect.DeepEqual(c.Servers[0].Aliases, []string{"y"}) {
+        t.Errorf("unexpected result: %v, %v", removed, err)
+    }
+    removed, err = D(&V, "servers", 0)
+    if err != nil || len(c.Servers) != 1 || c.Servers[0].Host != "b" || removed.(reflectServer).Host != "a" {
+        t.Errorf("unexpected result: %v, %v", removed, err)
+    }
+    if _, err = D(&V, "servers", 0, "labels", "env"); err != nil || len(c.Servers[0].Labels) != 0 {
+        t.Error(err)
+    }
+    // Map entries are updated via copy
+    if _, err = D(&V, "by_name", "c", "aliases"); !errors.Is(err, ErrTypeMismatch) {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if _, err = D(&V, "by_name", "c"); err != nil || len(c.ByName) != 0 {
+        t.Error(err)
+    }
+    if _, err = D(&V, "extra", "list", 0, "k"); err != nil || len(c.Extra["list"].([]map[string]interface{})[0]) != 0 {
+        t.Error(err)
+    }
+    var kerr KeyError
+    if _, err = D(&V, "extra", "missing"); !errors.As(err, &kerr) || kerr.Depth() != 1 {
+        t.Errorf("unexpected error: %v", err)
+    }
+    var terr TypeError
+    if _, err = D(&V, "matrix", 0, 1); !errors.As(err, &terr) || terr.Depth() != 2 {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if _, err = D(&V, "servers", 0, "host"); !errors.As(err, &terr) {
+        t.Errorf("unexpected error: %v", err)
+    }
+    // Top level native slice is replaced
+    V = []int{1, 2, 3}
+    if removed, err = D(&V, 1); err != nil || removed != 2 || !reflect.DeepEqual(V, []int{1, 3}) {
+        t.Errorf("unexpected result: %v, %v, %v", V, removed, err)
+    }
+}
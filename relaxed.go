@@ -0,0 +1,543 @@
+package qjson
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "math/big"
+    "strconv"
+    "strings"
+    "unicode"
+    "unicode/utf16"
+    "unicode/utf8"
+)
+
+// This error is returned by ParseRelaxed() when input can't be parsed. In
+// addition to ParseError data it carries line and column where problem was
+// detected.
+type SyntaxError struct {
+    ParseError
+    line   int
+    column int
+}
+
+func (e SyntaxError) Error() string {
+    where := fmt.Sprintf("line %d, column %d", e.line, e.column)
+    if len(e.path) > 0 {
+        where += fmt.Sprintf(", path %q", FormatPath(e.path...))
+    }
+    return fmt.Sprintf("Syntax error at %s: %s", where, e.msg)
+}
+
+// Makes errors.As() match SyntaxError with ParseError target, so both
+// Parse() and ParseRelaxed() errors can be handled the same way.
+func (e SyntaxError) As(target interface{}) bool {
+    if t, ok := target.(*ParseError); ok {
+        *t = e.ParseError
+        return true
+    }
+    return false
+}
+
+// Returns line number where error was detected, starting from 1.
+func (e SyntaxError) Line() int {
+    return e.line
+}
+
+// Returns column number in characters where error was detected, starting
+// from 1.
+func (e SyntaxError) Column() int {
+    return e.column
+}
+
+// Same as Parse(), but accepts relaxed syntax of JSONC and JSON5: `//` and
+// `/* */` comments, trailing commas in objects and arrays, unquoted
+// identifier keys, single-quoted strings, hexadecimal numbers, numbers with
+// leading plus sign or leading or trailing decimal point and additional
+// string escapes. Result consists of the same types Parse() produces.
+// Problems are reported with SyntaxError. Without MaxDepth() option nesting
+// is limited to 10000 levels.
+func ParseRelaxed(data []byte, opts ...ParseOption) (interface{}, error) {
+    p := &relaxedParser{data: data}
+    for _, opt := range opts {
+        opt(&p.opts)
+    }
+    if p.opts.maxSize > 0 && int64(len(data)) > p.opts.maxSize {
+        p.pos = int(p.opts.maxSize)
+        return nil, p.fail(ErrLimitExceeded, "Input is longer than %d bytes", p.opts.maxSize)
+    }
+    return p.parse()
+}
+
+// Same as ParseRelaxed(), but accepts string.
+func ParseRelaxedString(data string, opts ...ParseOption) (interface{}, error) {
+    return ParseRelaxed([]byte(data), opts...)
+}
+
+// Same as ParseRelaxed(), but reads whole input from r first. Read errors
+// are reported with ParseError.
+func ParseRelaxedReader(r io.Reader, opts ...ParseOption) (interface{}, error) {
+    var o parseOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+    if o.maxSize > 0 {
+        r = io.LimitReader(r, o.maxSize+1)
+    }
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, ParseError{offset: int64(len(data)), msg: err.Error(), kind: err}
+    }
+    return ParseRelaxed(data, opts...)
+}
+
+type relaxedParser struct {
+    data []byte
+    pos  int
+    opts parseOptions
+    path []interface{}
+}
+
+func (p *relaxedParser) fail(kind error, format string, args ...interface{}) error {
+    line, column := 1, 1
+    for _, r := range string(p.data[:p.pos]) {
+        if r == '\n' {
+            line++
+            column = 1
+        } else {
+            column++
+        }
+    }
+    return SyntaxError{
+        ParseError: ParseError{
+            offset: int64(p.pos),
+            path:   append([]interface{}{}, p.path...),
+            msg:    fmt.Sprintf(format, args...),
+            kind:   kind,
+        },
+        line:   line,
+        column: column,
+    }
+}
+
+func (p *relaxedParser) unexpected(what string) error {
+    if p.pos >= len(p.data) {
+        return p.fail(ErrSyntax, "Unexpected end of input, expecting %s", what)
+    }
+    r, _ := utf8.DecodeRune(p.data[p.pos:])
+    return p.fail(ErrSyntax, "Unexpected character %q, expecting %s", r, what)
+}
+
+func (p *relaxedParser) peek() byte {
+    if p.pos < len(p.data) {
+        return p.data[p.pos]
+    }
+    return 0
+}
+
+// Skips whitespace and comments.
+func (p *relaxedParser) skip() error {
+    for p.pos < len(p.data) {
+        switch c := p.data[p.pos]; {
+        case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f':
+            p.pos++
+        case c == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '/':
+            end := bytes.IndexByte(p.data[p.pos:], '\n')
+            if end < 0 {
+                p.pos = len(p.data)
+            } else {
+                p.pos += end + 1
+            }
+        case c == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '*':
+            end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+            if end < 0 {
+                return p.fail(ErrSyntax, "Unterminated comment")
+            }
+            p.pos += end + 4
+        case c >= utf8.RuneSelf:
+            r, size := utf8.DecodeRune(p.data[p.pos:])
+            if r != '\uFEFF' && !unicode.Is(unicode.Zs, r) && r != '\u2028' && r != '\u2029' {
+                return nil
+            }
+            p.pos += size
+        default:
+            return nil
+        }
+    }
+    return nil
+}
+
+func (p *relaxedParser) parse() (interface{}, error) {
+    if err := p.skip(); err != nil {
+        return nil, err
+    }
+    res, err := p.value(0)
+    if err != nil {
+        return nil, err
+    }
+    if p.opts.noTrailing {
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        if p.pos < len(p.data) {
+            return nil, p.fail(ErrSyntax, "Unexpected data after top-level value")
+        }
+    }
+    return res, nil
+}
+
+func (p *relaxedParser) value(depth int) (interface{}, error) {
+    switch c := p.peek(); {
+    case c == '{' || c == '[':
+        depth++
+        limit := p.opts.maxDepth
+        if limit <= 0 {
+            limit = defaultMaxDepth
+        }
+        if depth > limit {
+            return nil, p.fail(ErrLimitExceeded, "Nesting depth exceeds %d", limit)
+        }
+        if c == '{' {
+            return p.object(depth)
+        }
+        return p.array(depth)
+    case c == '"' || c == '\'':
+        return p.str()
+    case c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+        return p.number()
+    case isIdentStart(c):
+        start := p.pos
+        ident, err := p.ident()
+        if err != nil {
+            return nil, err
+        }
+        switch ident {
+        case "true":
+            return true, nil
+        case "false":
+            return false, nil
+        case "null":
+            return nil, nil
+        }
+        p.pos = start
+        return nil, p.fail(ErrSyntax, "Unexpected identifier %q", ident)
+    default:
+        return nil, p.unexpected("value")
+    }
+}
+
+func isIdentStart(c byte) bool {
+    return c == '_' || c == '$' || c == '\\' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+// Parses ECMAScript identifier used as unquoted key or literal.
+func (p *relaxedParser) ident() (string, error) {
+    var sb strings.Builder
+    for p.pos < len(p.data) {
+        r, size := utf8.DecodeRune(p.data[p.pos:])
+        if r == '\\' {
+            if p.pos+1 >= len(p.data) || p.data[p.pos+1] != 'u' {
+                p.pos++
+                return "", p.unexpected("'u'")
+            }
+            p.pos += 2
+            r, err := p.hex(4)
+            if err != nil {
+                return "", err
+            }
+            sb.WriteRune(rune(r))
+            continue
+        }
+        if !(r == '_' || r == '$' || unicode.IsLetter(r) ||
+            sb.Len() > 0 && (unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) ||
+                r == '\u200C' || r == '\u200D')) {
+            break
+        }
+        sb.WriteRune(r)
+        p.pos += size
+    }
+    if sb.Len() == 0 {
+        return "", p.unexpected("identifier")
+    }
+    return sb.String(), nil
+}
+
+func (p *relaxedParser) hex(n int) (uint64, error) {
+    if p.pos+n > len(p.data) {
+        p.pos = len(p.data)
+        return 0, p.unexpected("hex digit")
+    }
+    v, err := strconv.ParseUint(string(p.data[p.pos:p.pos+n]), 16, 32)
+    if err != nil {
+        return 0, p.fail(ErrSyntax, "Bad hex escape %q", p.data[p.pos:p.pos+n])
+    }
+    p.pos += n
+    return v, nil
+}
+
+func (p *relaxedParser) str() (string, error) {
+    quote := p.data[p.pos]
+    p.pos++
+    var sb strings.Builder
+    for p.pos < len(p.data) {
+        c := p.data[p.pos]
+        switch {
+        case c == quote:
+            p.pos++
+            return sb.String(), nil
+        case c == '\\':
+            p.pos++
+            if p.pos >= len(p.data) {
+                return "", p.fail(ErrSyntax, "Unterminated string")
+            }
+            e := p.data[p.pos]
+            p.pos++
+            switch e {
+            case 'b':
+                sb.WriteByte('\b')
+            case 'f':
+                sb.WriteByte('\f')
+            case 'n':
+                sb.WriteByte('\n')
+            case 'r':
+                sb.WriteByte('\r')
+            case 't':
+                sb.WriteByte('\t')
+            case 'v':
+                sb.WriteByte('\v')
+            case '0':
+                if c := p.peek(); c >= '0' && c <= '9' {
+                    return "", p.fail(ErrSyntax, "Octal escapes are not allowed")
+                }
+                sb.WriteByte(0)
+            case 'x':
+                v, err := p.hex(2)
+                if err != nil {
+                    return "", err
+                }
+                sb.WriteRune(rune(v))
+            case 'u':
+                v, err := p.hex(4)
+                if err != nil {
+                    return "", err
+                }
+                r := rune(v)
+                if utf16.IsSurrogate(r) && bytes.HasPrefix(p.data[p.pos:], []byte("\\u")) {
+                    save := p.pos
+                    p.pos += 2
+                    v2, err := p.hex(4)
+                    if err == nil && utf16.DecodeRune(r, rune(v2)) != unicode.ReplacementChar {
+                        r = utf16.DecodeRune(r, rune(v2))
+                    } else {
+                        p.pos = save
+                    }
+                }
+                sb.WriteRune(r)
+            case '\r':
+                // Line continuation
+                if p.peek() == '\n' {
+                    p.pos++
+                }
+            case '\n':
+            default:
+                if e >= '1' && e <= '9' {
+                    p.pos--
+                    return "", p.fail(ErrSyntax, "Octal escapes are not allowed")
+                }
+                // Other characters, including quotes, stand for themselves
+                p.pos--
+                r, size := utf8.DecodeRune(p.data[p.pos:])
+                if r == '\u2028' || r == '\u2029' {
+                    // Line continuation
+                    p.pos += size
+                    continue
+                }
+                sb.WriteRune(r)
+                p.pos += size
+            }
+        case c == '\n' || c == '\r':
+            return "", p.fail(ErrSyntax, "Unescaped line break in string")
+        default:
+            sb.WriteByte(c)
+            p.pos++
+        }
+    }
+    return "", p.fail(ErrSyntax, "Unterminated string")
+}
+
+func (p *relaxedParser) digits(hex bool) string {
+    start := p.pos
+    for p.pos < len(p.data) {
+        c := p.data[p.pos]
+        if !(c >= '0' && c <= '9' || hex && (c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F')) {
+            break
+        }
+        p.pos++
+    }
+    return string(p.data[start:p.pos])
+}
+
+func (p *relaxedParser) number() (interface{}, error) {
+    start := p.pos
+    sign := ""
+    if c := p.peek(); c == '-' || c == '+' {
+        if c == '-' {
+            sign = "-"
+        }
+        p.pos++
+    }
+    var text string
+    if p.peek() == '0' && p.pos+1 < len(p.data) && (p.data[p.pos+1] == 'x' || p.data[p.pos+1] == 'X') {
+        p.pos += 2
+        digits := p.digits(true)
+        if digits == "" {
+            return nil, p.unexpected("hex digit")
+        }
+        n, _ := new(big.Int).SetString(digits, 16)
+        text = sign + n.String()
+    } else {
+        intPart := p.digits(false)
+        if len(intPart) > 1 && intPart[0] == '0' {
+            p.pos = start
+            return nil, p.fail(ErrSyntax, "Leading zeros are not allowed")
+        }
+        var frac, exp string
+        if p.peek() == '.' {
+            p.pos++
+            frac = p.digits(false)
+        }
+        if intPart == "" && frac == "" {
+            return nil, p.unexpected("digit")
+        }
+        if c := p.peek(); c == 'e' || c == 'E' {
+            p.pos++
+            expSign := ""
+            if c := p.peek(); c == '-' || c == '+' {
+                expSign = string(c)
+                p.pos++
+            }
+            digits := p.digits(false)
+            if digits == "" {
+                return nil, p.unexpected("digit")
+            }
+            exp = "e" + expSign + digits
+        }
+        if intPart == "" {
+            intPart = "0"
+        }
+        text = sign + intPart
+        if frac != "" {
+            text += "." + frac
+        }
+        text += exp
+    }
+    if c := p.peek(); isIdentStart(c) || c >= '0' && c <= '9' {
+        return nil, p.unexpected("end of number")
+    }
+    if p.opts.useNumber {
+        return json.Number(text), nil
+    }
+    f, err := strconv.ParseFloat(text, 64)
+    if err != nil {
+        p.pos = start
+        return nil, p.fail(ErrSyntax, "Number %s is out of float64 range", text)
+    }
+    return f, nil
+}
+
+func (p *relaxedParser) object(depth int) (interface{}, error) {
+    m := make(map[string]interface{})
+    var o *Object
+    if p.opts.ordered {
+        o = &Object{values: m}
+    }
+    p.pos++
+    for {
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        if p.peek() == '}' {
+            p.pos++
+            break
+        }
+        var key string
+        var err error
+        switch c := p.peek(); {
+        case c == '"' || c == '\'':
+            key, err = p.str()
+        case isIdentStart(c):
+            key, err = p.ident()
+        default:
+            err = p.unexpected("key or '}'")
+        }
+        if err != nil {
+            return nil, err
+        }
+        if _, ok := m[key]; ok && p.opts.rejectDuplicates {
+            return nil, p.fail(ErrDuplicateKey, "Duplicate key %q", key)
+        }
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        if p.peek() != ':' {
+            return nil, p.unexpected("':'")
+        }
+        p.pos++
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        p.path = append(p.path, key)
+        elem, err := p.value(depth)
+        if err != nil {
+            return nil, err
+        }
+        p.path = p.path[:len(p.path)-1]
+        if o != nil {
+            o.Set(key, elem)
+        } else {
+            m[key] = elem
+        }
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        if p.peek() == ',' {
+            p.pos++
+        } else if p.peek() != '}' {
+            return nil, p.unexpected("',' or '}'")
+        }
+    }
+    if o != nil {
+        return o, nil
+    }
+    return m, nil
+}
+
+func (p *relaxedParser) array(depth int) (interface{}, error) {
+    a := make([]interface{}, 0)
+    p.pos++
+    for {
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        if p.peek() == ']' {
+            p.pos++
+            return a, nil
+        }
+        p.path = append(p.path, len(a))
+        elem, err := p.value(depth)
+        if err != nil {
+            return nil, err
+        }
+        p.path = p.path[:len(p.path)-1]
+        a = append(a, elem)
+        if err := p.skip(); err != nil {
+            return nil, err
+        }
+        if p.peek() == ',' {
+            p.pos++
+        } else if p.peek() != ']' {
+            return nil, p.unexpected("',' or ']'")
+        }
+    }
+}
@@ -0,0 +1,118 @@
+package qjson
+
+import (
+    "encoding/json"
+    "errors"
+    "strings"
+    "testing"
+)
+
+const RELAXED_CONFIG = `// Service configuration
+{
+    name: 'frontend',   /* single quotes */
+    "listen": {
+        port: 0x1F90,
+        $host: "0.0.0.0",
+    },
+    ratio: .5,
+    scale: +2.,
+    tags: [
+        'a\'b',
+        "multi\
+line",
+        '\x41B',
+    ],
+    /* trailing comma above
+       and below */
+}
+`
+
+func TestParseRelaxed(t *testing.T) {
+    j, err := ParseRelaxed([]byte(RELAXED_CONFIG))
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := `{"listen":{"$host":"0.0.0.0","port":8080},"name":"frontend","ratio":0.5,"scale":2,"tags":["a'b","multiline","AB"]}`
+    if dumpJSON(j, t) != expected {
+        t.Errorf("unexpected result: %s", dumpJSON(j, t))
+    }
+    // Result is usable with Q() and U()
+    if port, err := QInt(j, "listen", "port"); err != nil || port != 8080 {
+        t.Fail()
+    }
+    if _, err := U(&j, "tags", Append, "c"); err != nil {
+        t.Fail()
+    }
+    if n, err := QString(j, "tags", 3); err != nil || n != "c" {
+        t.Fail()
+    }
+    j, err = ParseRelaxedString(`{b: -0xff, a: 1e2, c: 0.5e-1}`, UseNumber(), OrderedObjects())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if dumpJSON(j, t) != `{"b":-255,"a":1e2,"c":0.5e-1}` {
+        t.Fail()
+    }
+    if v, err := Q(j, "b"); err != nil || v != json.Number("-255") {
+        t.Fail()
+    }
+    // Plain JSON is accepted as well
+    j, err = ParseRelaxedString(EXAMPLE2)
+    if err != nil || !equal(j, loadJSON(EXAMPLE2, t)) {
+        t.Fail()
+    }
+    j, err = ParseRelaxedReader(strings.NewReader("[1,]\n"), DisallowTrailingData())
+    if err != nil || dumpJSON(j, t) != `[1]` {
+        t.Fail()
+    }
+}
+
+func TestParseRelaxedErrors(t *testing.T) {
+    var serr SyntaxError
+    _, err := ParseRelaxedString("{\n    a: 1,\n    b: [1, 2,, 3],\n}")
+    if !errors.As(err, &serr) || !errors.Is(err, ErrSyntax) || serr.Line() != 3 ||
+        serr.Column() != 14 || serr.Offset() != 25 || FormatPath(serr.Path()...) != "b[2]" {
+        t.Errorf("unexpected error: %v", err)
+    }
+    if err.Error() != `Syntax error at line 3, column 14, path "b[2]": Unexpected character ',', expecting value` {
+        t.Errorf("unexpected message: %v", err)
+    }
+    for _, input := range []string{
+        ``, `// only comment`, `/* unterminated`, `{a 1}`, `[1 2]`, `{,}`, `[,]`, `'abc`,
+        "'a\nb'", `01`, `0x`, `1e`, `.`, `+`, `NaN`, `Infinity`, `undefined`, `0x1G`, `{1: 2}`,
+        `"\1"`, `1e400`, `[1, 2`,
+    } {
+        if _, err = ParseRelaxedString(input); !errors.Is(err, ErrSyntax) {
+            t.Errorf("input %q: %v", input, err)
+        }
+    }
+    _, err = ParseRelaxedString(`[1] // comment is not trailing data`, DisallowTrailingData())
+    if err != nil {
+        t.Fail()
+    }
+    _, err = ParseRelaxedString(`[1] x`, DisallowTrailingData())
+    if !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+    _, err = ParseRelaxedString(`{a: 1, 'a': 2}`, RejectDuplicateKeys())
+    if !errors.Is(err, ErrDuplicateKey) {
+        t.Fail()
+    }
+    var perr ParseError
+    _, err = ParseRelaxedString(`{a: [1, }`)
+    if !errors.As(err, &perr) || perr.Offset() != 8 || FormatPath(perr.Path()...) != "a[1]" {
+        t.Errorf("unexpected error: %v", err)
+    }
+    _, err = ParseRelaxedString(strings.Repeat("[", 1000000))
+    if !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    _, err = ParseRelaxedString(`[[[1]]]`, MaxDepth(2))
+    if !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    _, err = ParseRelaxedReader(strings.NewReader(`[1, 2, 3]`), MaxSize(4))
+    if !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+}
@@ -0,0 +1,281 @@
+package qjson
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+)
+
+// Layout of object or array scanned by scanner.container().
+type containerInfo struct {
+    found    bool   // member addressed by key exists
+    count    int    // number of members scanned
+    open     int    // position of opening bracket
+    lastEnd  int    // end of last scanned member, zero if container is empty
+    lastSep  []byte // whitespace preceding last member
+    colonSep []byte // whitespace after colon of last object member
+}
+
+// Scans container starting at current position until member addressed by
+// key is found or closing bracket is reached. If member is found, position
+// is left at the start of its value.
+func (s *scanner) container(key interface{}) (containerInfo, error) {
+    info := containerInfo{open: s.pos}
+    object := s.peek() == '{'
+    closing := byte(']')
+    if object {
+        closing = '}'
+    }
+    s.pos++
+    for {
+        sepStart := s.pos
+        s.ws()
+        if s.peek() == closing && info.count == 0 {
+            return info, nil
+        }
+        sep := s.data[sepStart:s.pos]
+        var colonSep []byte
+        if object {
+            raw, err := s.key()
+            if err != nil {
+                return info, err
+            }
+            colonStart := s.pos
+            s.ws()
+            colonSep = s.data[colonStart:s.pos]
+            if k, ok := key.(string); ok && keyEquals(raw, k) {
+                info.found = true
+                return info, nil
+            }
+        } else {
+            s.ws()
+            if k, ok := key.(int); ok && k == info.count {
+                info.found = true
+                return info, nil
+            }
+        }
+        if _, _, err := s.value(); err != nil {
+            return info, err
+        }
+        info.count++
+        info.lastEnd, info.lastSep, info.colonSep = s.pos, sep, colonSep
+        s.ws()
+        switch s.peek() {
+        case ',':
+            s.pos++
+        case closing:
+            return info, nil
+        default:
+            return info, s.unexpected(fmt.Sprintf("',' or '%c'", closing))
+        }
+    }
+}
+
+// Encodes value without HTML escaping. If indent is not empty, value is
+// formatted the same way as json.MarshalIndent() does.
+func encodeJSON(V interface{}, prefix, indent string) []byte {
+    var buf bytes.Buffer
+    enc := json.NewEncoder(&buf)
+    enc.SetEscapeHTML(false)
+    enc.SetIndent(prefix, indent)
+    // Normalized values are always encodable
+    enc.Encode(V)
+    return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// Returns indentation unit used by document or empty string if document is
+// written in a single line.
+func detectIndent(data []byte) string {
+    for i := bytes.IndexByte(data, '\n'); i >= 0; {
+        j := i + 1
+        for j < len(data) && (data[j] == ' ' || data[j] == '\t') {
+            j++
+        }
+        if j > i+1 {
+            return string(data[i+1 : j])
+        }
+        next := bytes.IndexByte(data[j:], '\n')
+        if next < 0 {
+            break
+        }
+        i = j + next
+    }
+    return ""
+}
+
+// Returns leading whitespace of line containing position pos.
+func lineIndent(data []byte, pos int) string {
+    start := bytes.LastIndexByte(data[:pos], '\n') + 1
+    end := start
+    for end < pos && (data[end] == ' ' || data[end] == '\t') {
+        end++
+    }
+    return string(data[start:end])
+}
+
+// Returns whitespace following last newline in sep. Second result reports
+// whether sep has newline at all.
+func sepIndent(sep []byte) (string, bool) {
+    i := bytes.LastIndexByte(sep, '\n')
+    if i < 0 {
+        return "", false
+    }
+    return string(sep[i+1:]), true
+}
+
+// Replaces Append keys with zero index for building new subtree.
+func appendToZero(keys []interface{}) []interface{} {
+    res := make([]interface{}, len(keys))
+    for i, k := range keys {
+        if _, ok := k.(appendKey); ok {
+            k = 0
+        }
+        res[i] = k
+    }
+    return res
+}
+
+func splice(data []byte, start, end int, insert ...[]byte) []byte {
+    n := len(data) - (end - start)
+    for _, b := range insert {
+        n += len(b)
+    }
+    res := make([]byte, 0, n)
+    res = append(res, data[:start]...)
+    for _, b := range insert {
+        res = append(res, b...)
+    }
+    return append(res, data[end:]...)
+}
+
+// Same as U(), but works with raw JSON data. New value is encoded and
+// spliced into copy of data, so everything outside of changed value is left
+// byte-identical. Missing objects and arrays on the path are created like
+// U() does. Values inserted into indented documents are indented to match
+// their surroundings. Returns updated copy of data; data itself is not
+// modified. Unlike U(), first occurrence of repeated key is updated. Input
+// nested deeper than 10000 levels is rejected like QBytes() does.
+// Invocation: UBytes(data []byte, path... interface{}, newvalue interface{}).
+func UBytes(data []byte, keys ...interface{}) ([]byte, error) {
+    l := len(keys)
+    if l < 1 {
+        return nil, newArgError("Incorrect arg length")
+    }
+    path := keys[:l-1]
+    newval, err := normalizeAt(path, keys[l-1])
+    if err != nil {
+        return nil, err
+    }
+    unit := detectIndent(data)
+    // Encodes value which is placed at position pos
+    encode := func(V interface{}, pos int) []byte {
+        if unit == "" {
+            return encodeJSON(V, "", "")
+        }
+        return encodeJSON(V, lineIndent(data, pos), unit)
+    }
+    // Builds subtree for the rest of path
+    subtree := func(i int) (interface{}, error) {
+        rest := appendToZero(append(append([]interface{}{}, path[i:]...), newval))
+        tree, err := s(rest...)
+        if err != nil {
+            return nil, locate(err, path, i, nil)
+        }
+        return tree, nil
+    }
+
+    sc := scanner{data: data}
+    sc.ws()
+    for i, key := range path {
+        kind := byteKind(sc.peek())
+        if sc.peek() == 'n' {
+            // Recreate subtree in place of null
+            start, end, err := sc.value()
+            if err != nil {
+                return nil, err
+            }
+            tree, err := subtree(i)
+            if err != nil {
+                return nil, err
+            }
+            return splice(data, start, end, encode(tree, start)), nil
+        }
+        switch k := key.(type) {
+        case string:
+            if sc.peek() != '{' {
+                if _, _, err := sc.value(); err != nil {
+                    return nil, err
+                }
+                return nil, locate(newTypeError("Container type mismatch"), path, i, kind)
+            }
+        case int, appendKey:
+            if sc.peek() != '[' {
+                if _, _, err := sc.value(); err != nil {
+                    return nil, err
+                }
+                return nil, locate(newTypeError("Container type mismatch"), path, i, kind)
+            }
+            if k, ok := k.(int); ok && k < 0 {
+                return nil, locate(newIndexError(k), path, i, kind)
+            }
+        default:
+            return nil, locate(newTypeError("Unknown key type"), path, i, kind)
+        }
+        info, err := sc.container(key)
+        if err != nil {
+            return nil, err
+        }
+        if info.found {
+            sc.path = append(sc.path, key)
+            continue
+        }
+
+        if info.count == 0 {
+            // Empty container has no members to follow, so it is replaced
+            // with new one formatted like recreated subtrees are
+            tree, err := subtree(i)
+            if err != nil {
+                return nil, err
+            }
+            return splice(data, info.open, sc.pos+1, encode(tree, info.open)), nil
+        }
+
+        // Insert missing members after the last one
+        tree, err := subtree(i + 1)
+        if err != nil {
+            return nil, err
+        }
+        var members [][]byte
+        if _, ok := key.(string); ok {
+            members = append(members, encodeJSON(key, "", ""))
+        } else {
+            n := 0
+            if k, ok := key.(int); ok {
+                n = k - info.count
+            }
+            for j := 0; j < n; j++ {
+                members = append(members, []byte("null"))
+            }
+        }
+        indent, multiline := sepIndent(info.lastSep)
+        value := encodeJSON(tree, "", "")
+        if multiline && unit != "" {
+            value = encodeJSON(tree, indent, unit)
+        }
+        var insert [][]byte
+        if _, ok := key.(string); ok {
+            insert = [][]byte{[]byte(","), info.lastSep, members[0], []byte(":"), info.colonSep, value}
+        } else {
+            for _, m := range members {
+                insert = append(insert, []byte(","), info.lastSep, m)
+            }
+            insert = append(insert, []byte(","), info.lastSep, value)
+        }
+        return splice(data, info.lastEnd, info.lastEnd, insert...), nil
+    }
+    start, end, err := sc.value()
+    if err != nil {
+        return nil, err
+    }
+    return splice(data, start, end, encode(newval, start)), nil
+}
@@ -0,0 +1,212 @@
+package qjson
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+const SPLICE_CONFIG = `{
+  "name": "svc",   "version": 1,
+  "server": {
+    "hosts": [
+      "a",
+      "b"
+    ],
+    "opts": {}
+  },
+  "empty": [],
+  "nothing": null
+}
+`
+
+func TestUBytesReplace(t *testing.T) {
+    data := []byte(SPLICE_CONFIG)
+    res, err := UBytes(data, "version", 2)
+    if err != nil || string(res) != `{
+  "name": "svc",   "version": 2,
+  "server": {
+    "hosts": [
+      "a",
+      "b"
+    ],
+    "opts": {}
+  },
+  "empty": [],
+  "nothing": null
+}
+` {
+        t.Fail()
+    }
+    if string(data) != SPLICE_CONFIG {
+        t.Fail()
+    }
+    res, err = UBytes(data, "server", "hosts", 1, map[string]interface{}{"h": "c", "p": 1})
+    if err != nil || string(res) != `{
+  "name": "svc",   "version": 1,
+  "server": {
+    "hosts": [
+      "a",
+      {
+        "h": "c",
+        "p": 1
+      }
+    ],
+    "opts": {}
+  },
+  "empty": [],
+  "nothing": null
+}
+` {
+        t.Log(string(res))
+        t.Fail()
+    }
+    res, err = UBytes([]byte(` {"a":[1,{"b":true}]} `), "a", 1, "b", "<&>")
+    if err != nil || string(res) != ` {"a":[1,{"b":"<&>"}]} ` {
+        t.Fail()
+    }
+    res, err = UBytes([]byte(`[1]`), []int{2})
+    if err != nil || string(res) != `[2]` {
+        t.Fail()
+    }
+}
+
+func TestUBytesInsert(t *testing.T) {
+    data := []byte(SPLICE_CONFIG)
+    res, err := UBytes(data, "server", "hosts", Append, "c")
+    if err != nil || string(res) != `{
+  "name": "svc",   "version": 1,
+  "server": {
+    "hosts": [
+      "a",
+      "b",
+      "c"
+    ],
+    "opts": {}
+  },
+  "empty": [],
+  "nothing": null
+}
+` {
+        t.Log(string(res))
+        t.Fail()
+    }
+    res, err = UBytes(data, "server", "tls", "cert", "x.pem")
+    if err != nil || string(res) != `{
+  "name": "svc",   "version": 1,
+  "server": {
+    "hosts": [
+      "a",
+      "b"
+    ],
+    "opts": {},
+    "tls": {
+      "cert": "x.pem"
+    }
+  },
+  "empty": [],
+  "nothing": null
+}
+` {
+        t.Log(string(res))
+        t.Fail()
+    }
+    res, err = UBytes(data, "server", "opts", "debug", true)
+    if err != nil || !equal(loadJSON(string(res), t), loadJSON(`{"name": "svc", "version": 1,
+        "server": {"hosts": ["a", "b"], "opts": {"debug": true}}, "empty": [], "nothing": null}`, t)) {
+        t.Log(string(res))
+        t.Fail()
+    }
+    res, err = UBytes(data, "empty", 2, "x")
+    if err != nil {
+        t.Fail()
+    }
+    if v, _ := QBytes(res, "empty"); string(v) != `[
+    null,
+    null,
+    "x"
+  ]` {
+        t.Log(string(v))
+        t.Fail()
+    }
+    // Empty containers follow document formatting
+    res, err = UBytes([]byte("{\n  \"a\": {}\n}"), "a", "b", "k", 1)
+    if err != nil || string(res) != "{\n  \"a\": {\n    \"b\": {\n      \"k\": 1\n    }\n  }\n}" {
+        t.Errorf("unexpected result: %s, %v", res, err)
+    }
+    res, err = UBytes([]byte(`{"a":{ },"b":[]}`), "b", Append, "x")
+    if err != nil || string(res) != `{"a":{ },"b":["x"]}` {
+        t.Errorf("unexpected result: %s, %v", res, err)
+    }
+    res, err = UBytes([]byte(`{"a":{ },"b":[]}`), "a", "k", 1)
+    if err != nil || string(res) != `{"a":{"k":1},"b":[]}` {
+        t.Errorf("unexpected result: %s, %v", res, err)
+    }
+    res, err = UBytes(data, "nothing", 1, "k", "v")
+    if err != nil {
+        t.Fail()
+    }
+    if v, _ := QBytes(res, "nothing"); string(v) != `[
+    null,
+    {
+      "k": "v"
+    }
+  ]` {
+        t.Log(string(v))
+        t.Fail()
+    }
+    res, err = UBytes([]byte(`{"a": [1, 2]}`), "a", 4, 5)
+    if err != nil || string(res) != `{"a": [1, 2, null, null, 5]}` {
+        t.Fail()
+    }
+    res, err = UBytes([]byte(`null`), "a", Append, "b", 1)
+    if err != nil || string(res) != `{"a":[{"b":1}]}` {
+        t.Fail()
+    }
+    res, err = UBytes([]byte(`{"ab": 1}`), "ab", 2)
+    if err != nil || string(res) != `{"ab": 2}` {
+        t.Fail()
+    }
+}
+
+func TestUBytesErrors(t *testing.T) {
+    data := []byte(SPLICE_CONFIG)
+    var terr TypeError
+    _, err := UBytes(data, "name", "x", 1)
+    if !errors.As(err, &terr) || terr.Depth() != 1 || terr.Kind() != "string" ||
+        FormatPath(terr.Path()...) != "name.x" {
+        t.Fail()
+    }
+    deep := []byte(`{"a": ` + strings.Repeat("[", 1000000))
+    if _, err = UBytes(deep, "a", 1); !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    if _, err = UBytes(deep, "b", 1); !errors.Is(err, ErrLimitExceeded) {
+        t.Fail()
+    }
+    if _, err = UBytes(data, "server", 0, 1); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err = UBytes(data, "server", "hosts", -1, 1); !errors.Is(err, ErrIndexOutOfRange) {
+        t.Fail()
+    }
+    if _, err = UBytes(data, "server", 1.5, 1); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err = UBytes(data, "server", "new", -1, 1); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+    _, err = UBytes(data, "server", "f", func() {})
+    if !errors.As(err, &terr) || FormatPath(terr.Path()...) != "server.f" {
+        t.Fail()
+    }
+    if _, err = UBytes(data); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+    if _, err = UBytes([]byte(`{"a": [1, }`), "a", 5, 1); !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+    if _, err = UBytes([]byte(``), "a", 1); !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+}
@@ -0,0 +1,114 @@
+package qjson
+
+import (
+    "encoding/json"
+    "io"
+)
+
+// Same as Q(), but reads JSON document from r and decodes only the value
+// found at path. Subtrees which are not on the path are skipped without
+// being decoded and reading stops as soon as the value is decoded, so r may
+// be left positioned past it. Unlike Q(), first occurrence of repeated key
+// is used. Malformed input is reported with ParseError.
+func QReader(r io.Reader, keys ...interface{}) (interface{}, error) {
+    p := &parser{dec: json.NewDecoder(r)}
+    p.dec.UseNumber()
+    tok, err := p.token()
+    if err != nil {
+        return nil, err
+    }
+    for i, key := range keys {
+        next, err := p.follow(tok, key)
+        if err != nil {
+            return nil, locate(err, keys, i, tokenKind(tok))
+        }
+        tok = next
+    }
+    return p.value(tok, 0)
+}
+
+// Returns value of the same kind as value starting with token tok.
+func tokenKind(tok json.Token) interface{} {
+    switch tok {
+    case json.Delim('{'):
+        return map[string]interface{}(nil)
+    case json.Delim('['):
+        return []interface{}(nil)
+    default:
+        return tok
+    }
+}
+
+// Skips values within container which starts with token tok until value
+// addressed by key is reached. Returns first token of that value.
+func (p *parser) follow(tok json.Token, key interface{}) (json.Token, error) {
+    switch k := key.(type) {
+    case string:
+        if tok != json.Delim('{') {
+            return nil, newTypeError("Bad container type: not a map")
+        }
+        for p.dec.More() {
+            ktok, err := p.token()
+            if err != nil {
+                return nil, err
+            }
+            vtok, err := p.token()
+            if err != nil {
+                return nil, err
+            }
+            if ktok.(string) == k {
+                p.path = append(p.path, k)
+                return vtok, nil
+            }
+            if err := p.skip(vtok); err != nil {
+                return nil, err
+            }
+        }
+        return nil, newKeyError(k)
+    case int:
+        if tok != json.Delim('[') {
+            return nil, newTypeError("Bad container type: not an array")
+        }
+        if k < 0 {
+            return nil, newIndexError(k)
+        }
+        for i := 0; p.dec.More(); i++ {
+            vtok, err := p.token()
+            if err != nil {
+                return nil, err
+            }
+            if i == k {
+                p.path = append(p.path, k)
+                return vtok, nil
+            }
+            if err := p.skip(vtok); err != nil {
+                return nil, err
+            }
+        }
+        return nil, newIndexError(k)
+    case wildcardKey:
+        return nil, newArgError("Wildcard keys are supported only by QAll()")
+    default:
+        return nil, newTypeError("Unknown key type")
+    }
+}
+
+// Skips value which starts with token tok.
+func (p *parser) skip(tok json.Token) error {
+    if tok != json.Delim('{') && tok != json.Delim('[') {
+        return nil
+    }
+    for depth := 1; depth > 0; {
+        tok, err := p.token()
+        if err != nil {
+            return err
+        }
+        switch tok {
+        case json.Delim('{'), json.Delim('['):
+            depth++
+        case json.Delim('}'), json.Delim(']'):
+            depth--
+        }
+    }
+    return nil
+}
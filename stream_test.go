@@ -0,0 +1,83 @@
+package qjson
+
+import (
+    "errors"
+    "io"
+    "strings"
+    "testing"
+    "testing/iotest"
+)
+
+func TestQReader(t *testing.T) {
+    j := loadJSON(EXAMPLE, t)
+    paths := [][]interface{}{
+        {},
+        {"glossary", "title"},
+        {"glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossDef"},
+        {"glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossDef", "GlossSeeAlso", 1},
+        {"glossary", "GlossDiv", "GlossList", "GlossEntry", "GlossSee"},
+    }
+    for _, path := range paths {
+        expected, _ := Q(j, path...)
+        v, err := QReader(strings.NewReader(EXAMPLE), path...)
+        if err != nil || !equal(v, expected) {
+            t.Errorf("path %s: %v, %v", FormatPath(path...), v, err)
+        }
+    }
+    v, err := QReader(strings.NewReader(`[{"a": [1, {"b": 2}]}, {"a": 3}, 4]`), 1, "a")
+    if err != nil || v != 3.0 {
+        t.Fail()
+    }
+    v, err = QReader(strings.NewReader(`{"a": 1, "a": 2}`), "a")
+    if err != nil || v != 1.0 {
+        t.Fail()
+    }
+}
+
+func TestQReaderStopsEarly(t *testing.T) {
+    // Reader fails right after the value, so it must not be read further
+    r := io.MultiReader(
+        iotest.OneByteReader(strings.NewReader(`{"skip": {"a": [1, 2, {}]}, "x": {"y": "found"}`)),
+        iotest.ErrReader(io.ErrClosedPipe),
+    )
+    v, err := QReader(r, "x", "y")
+    if err != nil || v != "found" {
+        t.Fail()
+    }
+}
+
+func TestQReaderErrors(t *testing.T) {
+    var kerr KeyError
+    _, err := QReader(strings.NewReader(EXAMPLE), "glossary", "GlossDiv", "missing")
+    if !errors.As(err, &kerr) || kerr.Depth() != 2 || kerr.Kind() != "object" ||
+        FormatPath(kerr.Path()...) != "glossary.GlossDiv.missing" {
+        t.Fail()
+    }
+    var ierr IndexError
+    _, err = QReader(strings.NewReader(`{"a": [1, 2]}`), "a", 2)
+    if !errors.As(err, &ierr) || ierr.Depth() != 1 || ierr.Kind() != "array" {
+        t.Fail()
+    }
+    if _, err = QReader(strings.NewReader(`{"a": [1, 2]}`), "a", -1); !errors.Is(err, ErrIndexOutOfRange) {
+        t.Fail()
+    }
+    var terr TypeError
+    _, err = QReader(strings.NewReader(`{"a": "str"}`), "a", "b")
+    if !errors.As(err, &terr) || terr.Depth() != 1 || terr.Kind() != "string" {
+        t.Fail()
+    }
+    if _, err = QReader(strings.NewReader(`[1]`), "a"); !errors.Is(err, ErrTypeMismatch) {
+        t.Fail()
+    }
+    if _, err = QReader(strings.NewReader(`[1]`), Any); !errors.Is(err, ErrBadArgument) {
+        t.Fail()
+    }
+    var perr ParseError
+    _, err = QReader(strings.NewReader(`{"a": [1, }, "b": 1}`), "b")
+    if !errors.As(err, &perr) || !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+    if _, err = QReader(strings.NewReader(`{"a": {`), "b"); !errors.Is(err, ErrSyntax) {
+        t.Fail()
+    }
+}
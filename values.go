@@ -0,0 +1,77 @@
+package qjson
+
+// Compares two JSON values for structural equality.
+func equal(a, b interface{}) bool {
+    if isNull(a) || isNull(b) {
+        return isNull(a) && isNull(b)
+    }
+    // Ordered and unordered objects are equal if they have the same members
+    if x, ok := asMap(a); ok {
+        y, ok := asMap(b)
+        if !ok || len(x) != len(y) {
+            return false
+        }
+        for k, xv := range x {
+            yv, ok := y[k]
+            if !ok || !equal(xv, yv) {
+                return false
+            }
+        }
+        return true
+    }
+    switch x := a.(type) {
+    case []interface{}:
+        y, ok := b.([]interface{})
+        if !ok || len(x) != len(y) {
+            return false
+        }
+        for i := range x {
+            if !equal(x[i], y[i]) {
+                return false
+            }
+        }
+        return true
+    case string:
+        y, ok := b.(string)
+        return ok && x == y
+    case bool:
+        y, ok := b.(bool)
+        return ok && x == y
+    default:
+        if isNumber(a) && isNumber(b) {
+            res, ok := compareNumbers(a, b)
+            return ok && res == 0
+        }
+        return false
+    }
+}
+
+// Makes deep copy of JSON value. Containers are copied recursively, scalar
+// values are shared.
+func deepCopy(V interface{}) interface{} {
+    switch v := V.(type) {
+    case map[string]interface{}:
+        res := make(map[string]interface{}, len(v))
+        for k, elem := range v {
+            res[k] = deepCopy(elem)
+        }
+        return res
+    case *Object:
+        if v == nil {
+            return nil
+        }
+        res := &Object{keys: v.Keys(), values: make(map[string]interface{}, v.Len())}
+        for k, elem := range v.values {
+            res.values[k] = deepCopy(elem)
+        }
+        return res
+    case []interface{}:
+        res := make([]interface{}, len(v))
+        for i, elem := range v {
+            res[i] = deepCopy(elem)
+        }
+        return res
+    default:
+        return V
+    }
+}
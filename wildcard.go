@@ -0,0 +1,64 @@
+package qjson
+
+type wildcardKey int
+
+const (
+    // Special key for QAll() which matches every element of array and every
+    // value of object.
+    Any wildcardKey = iota
+    // Special key for QAll() which matches current value and all values
+    // nested in it at any depth. Following keys are applied to each of them.
+    Descend
+)
+
+func (k wildcardKey) String() string {
+    if k == Descend {
+        return ".."
+    }
+    return "*"
+}
+
+// Selects node itself and all its descendants.
+type jpDescendSelector struct{}
+
+func (jpDescendSelector) apply(root interface{}, n jpNode, out []jpNode) []jpNode {
+    out = append(out, n)
+    jpChildren(n, func(c jpNode) {
+        out = jpDescendSelector{}.apply(root, c, out)
+    })
+    return out
+}
+
+// Same as Q(), but also accepts Any and Descend special keys and returns all
+// matched values along with their concrete paths. Paths which do not exist
+// in document are not matched and do not cause an error.
+func QAll(V interface{}, keys ...interface{}) ([]Match, error) {
+    var segments []jpSegment
+    descend := false
+    for _, key := range keys {
+        var sel jpSelector
+        switch k := key.(type) {
+        case wildcardKey:
+            if k == Descend {
+                descend = true
+                continue
+            }
+            sel = jpWildcardSelector{}
+        case string:
+            sel = jpNameSelector(k)
+        case int:
+            if k < 0 {
+                return nil, newArgError("Negative index is not allowed")
+            }
+            sel = jpIndexSelector(k)
+        default:
+            return nil, newTypeError("Unknown key type")
+        }
+        segments = append(segments, jpSegment{descendant: descend, selectors: []jpSelector{sel}})
+        descend = false
+    }
+    if descend {
+        segments = append(segments, jpSegment{selectors: []jpSelector{jpDescendSelector{}}})
+    }
+    return (&JSONPath{segments: segments}).Query(V), nil
+}
@@ -0,0 +1,90 @@
+package qjson
+
+import (
+    "reflect"
+    "testing"
+)
+
+func matchPaths(matches []Match) []string {
+    res := []string{}
+    for _, m := range matches {
+        res = append(res, FormatPath(m.Path...))
+    }
+    return res
+}
+
+func TestQAllAny(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    matches, err := QAll(j, "menu", "popup", "menuitem", Any, "value")
+    if err != nil {
+        t.FailNow()
+    }
+    values := []interface{}{}
+    for _, m := range matches {
+        values = append(values, m.Value)
+    }
+    if !reflect.DeepEqual(values, []interface{}{"New", "Open", "Close"}) {
+        t.Fail()
+    }
+    ref := []string{
+        "menu.popup.menuitem[0].value",
+        "menu.popup.menuitem[1].value",
+        "menu.popup.menuitem[2].value",
+    }
+    if !reflect.DeepEqual(matchPaths(matches), ref) {
+        t.Fail()
+    }
+    matches, err = QAll(j, "menu", Any)
+    if err != nil || !reflect.DeepEqual(matchPaths(matches), []string{"menu.id", "menu.popup", "menu.value"}) {
+        t.Fail()
+    }
+}
+
+func TestQAllDescend(t *testing.T) {
+    j := loadJSON(`{"a": {"value": 1, "b": [{"value": 2}, {"c": {"value": 3}}]}}`, t)
+    matches, err := QAll(j, Descend, "value")
+    if err != nil {
+        t.FailNow()
+    }
+    ref := []string{"a.value", "a.b[0].value", "a.b[1].c.value"}
+    if !reflect.DeepEqual(matchPaths(matches), ref) {
+        t.Error(matchPaths(matches))
+    }
+    matches, err = QAll(j, "a", "b", Descend)
+    ref = []string{"a.b", "a.b[0]", "a.b[0].value", "a.b[1]", "a.b[1].c", "a.b[1].c.value"}
+    if err != nil || !reflect.DeepEqual(matchPaths(matches), ref) {
+        t.Error(matchPaths(matches))
+    }
+}
+
+func TestQAllConcrete(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    matches, err := QAll(j, "menu", "id")
+    if err != nil || len(matches) != 1 || matches[0].Value != "file" {
+        t.Fail()
+    }
+    matches, err = QAll(j, "menu", "nonexistent")
+    if err != nil || len(matches) != 0 {
+        t.Fail()
+    }
+    matches, err = QAll(j)
+    if err != nil || len(matches) != 1 || len(matches[0].Path) != 0 {
+        t.Fail()
+    }
+}
+
+func TestQAllBadKeys(t *testing.T) {
+    j := loadJSON(EXAMPLE2, t)
+    _, err := QAll(j, "menu", -1)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+    _, err = QAll(j, "menu", .0)
+    if _, ok := err.(TypeError) ; !ok {
+        t.Fail()
+    }
+    _, err = Q(j, "menu", Any)
+    if _, ok := err.(ArgError) ; !ok {
+        t.Fail()
+    }
+}